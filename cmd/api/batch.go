@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/comfortliner/greenlight/internal/data"
+	"github.com/comfortliner/greenlight/internal/validator"
+)
+
+// Limit the number of operations of a single batch.
+const maxBatchOperations = 100
+
+// batchOperation struct represents a single operation of a batch.
+type batchOperation struct {
+	Op      string `json:"op"`      // One of create, update or delete.
+	ID      int64  `json:"id"`      // ID of the updated or deleted movie.
+	Version int32  `json:"version"` // Expected version of the updated movie.
+	Movie   struct {
+		Title       *string    `json:"title"`
+		Year        *int32     `json:"year"`
+		Runtime     *int32     `json:"runtime"`
+		Genres      []string   `json:"genres"`
+		Status      *string    `json:"status"`
+		ReleaseDate *data.Date `json:"release_date"`
+
+		OriginalLanguage    *string           `json:"original_language"`
+		ProductionCountries []string          `json:"production_countries"`
+		SpokenLanguages     []string          `json:"spoken_languages"`
+		Certifications      map[string]string `json:"certifications"`
+		Plot                *string           `json:"plot"`
+		ExternalIDs         map[string]string `json:"external_ids"`
+	} `json:"movie"` // Fields of the created movie, or the changed fields of the updated movie.
+}
+
+// batchResult struct represents the result of a single operation of a batch.
+type batchResult struct {
+	Op     string      `json:"op"`
+	Status int         `json:"status"`
+	ID     int64       `json:"id"`
+	Movie  *data.Movie `json:"movie,omitempty"`
+}
+
+// batchError struct is returned by runBatchOperation() if an operation fails, which rolls back the whole batch.
+type batchError struct {
+	status  int
+	message any
+}
+
+func (e *batchError) Error() string {
+	return fmt.Sprintf("%d: %v", e.status, e.message)
+}
+
+// Add a batchMoviesHandler for the "POST /v1/movies/batch" endpoint.
+// All operations run in a single transaction. If an operation fails, the whole batch is rolled back and
+// the response reports the failed operation with its (zero-based) index.
+func (app *application) batchMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Operations []batchOperation `json:"operations"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(input.Operations) > 0, "operations", "must contain at least 1 operation")
+	v.Check(len(input.Operations) <= maxBatchOperations, "operations", fmt.Sprintf("must not contain more than %d operations", maxBatchOperations))
+
+	for i, op := range input.Operations {
+		key := fmt.Sprintf("operations[%d]", i)
+
+		v.Check(validator.PermittedValue(op.Op, "create", "update", "delete"), key+".op", "must be create, update or delete")
+		v.Check(op.Op == "create" || op.ID > 0, key+".id", "must be provided")
+		v.Check(op.Op != "update" || op.Version > 0, key+".version", "must be provided")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tx, err := app.models.Movies.BeginTx()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	defer tx.Rollback()
+
+	user := app.contextGetUser(r)
+
+	results := make([]*batchResult, 0, len(input.Operations))
+
+	for i, op := range input.Operations {
+		result, err := app.runBatchOperation(tx, op, user.ID)
+		if err != nil {
+			var batchErr *batchError
+
+			switch {
+			case errors.As(err, &batchErr):
+				app.errorResponse(w, r, batchErr.status, envelope{"operation": i, "op": op.Op, "message": batchErr.message})
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		results = append(results, result)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The runBatchOperation() helper runs a single operation of a batch within the transaction. If the operation
+// fails validation, conflicts or doesn't find its movie, a *batchError is returned.
+func (app *application) runBatchOperation(tx *data.MovieTx, op batchOperation, userID int64) (*batchResult, error) {
+	var movie *data.Movie
+
+	switch op.Op {
+	case "create":
+		movie = &data.Movie{Status: data.MovieReleased}
+	case "update":
+		var err error
+
+		movie, err = tx.Get(op.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				return nil, &batchError{http.StatusNotFound, "the requested resource could not be found"}
+			default:
+				return nil, err
+			}
+		}
+
+		if movie.Version != op.Version {
+			return nil, &batchError{http.StatusConflict, "unable to update the record due to an edit conflict, please try again"}
+		}
+	default:
+		err := tx.Delete(op.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				return nil, &batchError{http.StatusNotFound, "the requested resource could not be found"}
+			default:
+				return nil, err
+			}
+		}
+
+		return &batchResult{Op: op.Op, Status: http.StatusOK, ID: op.ID}, nil
+	}
+
+	if op.Movie.Title != nil {
+		movie.Title = *op.Movie.Title
+	}
+
+	if op.Movie.Year != nil {
+		movie.Year = *op.Movie.Year
+	}
+
+	if op.Movie.Runtime != nil {
+		movie.Runtime = *op.Movie.Runtime
+	}
+
+	if op.Movie.Genres != nil {
+		movie.Genres = op.Movie.Genres
+	}
+
+	if op.Movie.Status != nil {
+		movie.Status = *op.Movie.Status
+	}
+
+	if op.Movie.ReleaseDate != nil {
+		movie.ReleaseDate = op.Movie.ReleaseDate
+	}
+
+	if op.Movie.OriginalLanguage != nil {
+		movie.OriginalLanguage = *op.Movie.OriginalLanguage
+	}
+
+	if op.Movie.ProductionCountries != nil {
+		movie.ProductionCountries = op.Movie.ProductionCountries
+	}
+
+	if op.Movie.SpokenLanguages != nil {
+		movie.SpokenLanguages = op.Movie.SpokenLanguages
+	}
+
+	if op.Movie.Certifications != nil {
+		movie.Certifications = op.Movie.Certifications
+	}
+
+	if op.Movie.Plot != nil {
+		movie.Plot = *op.Movie.Plot
+	}
+
+	if op.Movie.ExternalIDs != nil {
+		movie.ExternalIDs = op.Movie.ExternalIDs
+	}
+
+	v := validator.New()
+
+	if data.ValidateMovie(v, movie); !v.Valid() {
+		return nil, &batchError{http.StatusUnprocessableEntity, v.Errors}
+	}
+
+	if op.Op == "create" {
+		err := tx.Insert(movie, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateExternalID):
+				return nil, &batchError{http.StatusUnprocessableEntity, map[string]string{"external_ids": "must not be assigned to another movie"}}
+			default:
+				return nil, err
+			}
+		}
+
+		return &batchResult{Op: op.Op, Status: http.StatusCreated, ID: movie.ID, Movie: movie}, nil
+	}
+
+	err := tx.Update(movie, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			return nil, &batchError{http.StatusConflict, "unable to update the record due to an edit conflict, please try again"}
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			return nil, &batchError{http.StatusUnprocessableEntity, map[string]string{"external_ids": "must not be assigned to another movie"}}
+		default:
+			return nil, err
+		}
+	}
+
+	return &batchResult{Op: op.Op, Status: http.StatusOK, ID: movie.ID, Movie: movie}, nil
+}
@@ -49,6 +49,12 @@ func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.
 	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
 }
 
+// The failedRowValidationResponse() method will be used to send a 422 Unprocessable Entity status code to the client.
+// The validation errors are keyed by the line number of the failed row.
+func (app *application) failedRowValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]map[string]string) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+}
+
 // The failedValidationResponse() method will be used to send a 422 Unprocessable Entity status code to the client.
 func (app *application) failedValidationResponseForm(w http.ResponseWriter, r *http.Request, formdata any, page string) {
 	data := app.newTemplateData(r)
@@ -67,6 +73,12 @@ func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Reque
 	app.errorResponse(w, r, http.StatusInternalServerError, message)
 }
 
+// The unsupportedMediaTypeResponse() method will be used to send a 415 Unsupported Media Type status code and JSON response to the client.
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf("the content type %q is not supported for this resource", r.Header.Get("Content-Type"))
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, message)
+}
+
 // The rateLimitExceededResponse() method will be used to send a 429 Too Many Requests status code and JSON response to the client.
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
@@ -79,6 +91,48 @@ func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Requ
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
 
+// The duplicateMovieResponse() method will be used to send a 409 Conflict status code and JSON response to the client,
+// which contains the IDs of the movies the new movie is a likely duplicate of.
+func (app *application) duplicateMovieResponse(w http.ResponseWriter, r *http.Request, duplicates []int64) {
+	env := envelope{
+		"error":      "the movie is a likely duplicate of an existing movie, use ?force=true to add it anyway",
+		"duplicates": duplicates,
+	}
+
+	err := app.writeJSON(w, http.StatusConflict, env, nil)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(500)
+	}
+}
+
+// The movieMergedResponse() method will be used to send a 301 Moved Permanently status code and JSON response to the client,
+// if the requested movie has been merged into another movie.
+func (app *application) movieMergedResponse(w http.ResponseWriter, r *http.Request, targetID int64) {
+	location := fmt.Sprintf("/v1/movies/%d", targetID)
+
+	headers := make(http.Header)
+	headers.Set("Location", location)
+
+	env := envelope{
+		"error":       "the movie has been merged into another movie",
+		"merged_into": targetID,
+		"location":    location,
+	}
+
+	err := app.writeJSON(w, http.StatusMovedPermanently, env, headers)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(500)
+	}
+}
+
+// The preconditionFailedResponse() method will be used to send a 412 Precondition Failed status code and JSON response to the client.
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the resource has been modified since you retrieved it, please fetch it again"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, message)
+}
+
 // The invalidCredentialsResponse() method will be used to send a 401 Unauthorized status code and JSON response to the client.
 func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invalid authentication credentials"
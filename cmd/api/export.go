@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/comfortliner/greenlight/internal/data"
+)
+
+// The exportFormat() helper returns the requested list format. The "format" query string parameter takes
+// precedence over the Accept header. If neither asks for an export format, "json" is returned.
+func (app *application) exportFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	accept := r.Header.Get("Accept")
+
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return "csv"
+	case strings.Contains(accept, "application/x-ndjson"):
+		return "ndjson"
+	default:
+		return "json"
+	}
+}
+
+// The exportMovies() helper streams all movies matching the filters as CSV or NDJSON file to the client.
+// Once the first row has been written, errors can't be sent to the client anymore and are only logged.
+func (app *application) exportMovies(w http.ResponseWriter, r *http.Request, format string, mq data.MovieQuery, filters data.Filters) {
+	// The export can take much longer than the write timeout of the server.
+	err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(5 * time.Minute))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var writeMovie func(movie *data.Movie) error
+	var flush func() error
+
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+
+		writeMovie = func(movie *data.Movie) error {
+			var releaseDate string
+			if movie.ReleaseDate != nil {
+				releaseDate = movie.ReleaseDate.Format("2006-01-02")
+			}
+
+			return cw.Write([]string{
+				strconv.FormatInt(movie.ID, 10),
+				movie.Title,
+				strconv.Itoa(int(movie.Year)),
+				strconv.Itoa(int(movie.Runtime)),
+				strings.Join(movie.Genres, ","),
+				movie.Status,
+				releaseDate,
+				movie.OriginalLanguage,
+				strings.Join(movie.ProductionCountries, ","),
+				strings.Join(movie.SpokenLanguages, ","),
+				joinPairs(movie.Certifications),
+				movie.Plot,
+				joinPairs(movie.ExternalIDs),
+				strconv.Itoa(int(movie.Version)),
+			})
+		}
+
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="movies.csv"`)
+		w.WriteHeader(http.StatusOK)
+
+		err = cw.Write([]string{"id", "title", "year", "runtime", "genres", "status", "release_date", "original_language",
+			"production_countries", "spoken_languages", "certifications", "plot", "external_ids", "version"})
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+
+	case "ndjson":
+		enc := json.NewEncoder(w)
+
+		writeMovie = func(movie *data.Movie) error {
+			return enc.Encode(movie)
+		}
+
+		flush = func() error {
+			return nil
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="movies.ndjson"`)
+		w.WriteHeader(http.StatusOK)
+	}
+
+	err = app.models.Movies.Stream(mq, filters, writeMovie)
+	if err != nil {
+		app.logError(r, err)
+		return
+	}
+
+	err = flush()
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// The joinPairs() helper formats a map as comma separated "key:value" pairs ordered by key, e.g. "DE:12,US:R".
+// It is the CSV format of the certifications and external IDs, which is read by splitPairs().
+func joinPairs(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, key+":"+value)
+	}
+
+	slices.Sort(pairs)
+
+	return strings.Join(pairs, ",")
+}
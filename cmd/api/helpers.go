@@ -2,16 +2,20 @@ package main
 
 import (
 	"bytes"
+	"cmp"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/comfortliner/greenlight/internal/data"
 	"github.com/comfortliner/greenlight/internal/validator"
 	"github.com/go-playground/form/v4"
 	"github.com/julienschmidt/httprouter"
@@ -30,6 +34,167 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// Retrieve the "movie_id" URL Parameter from the current request context and convert it to an integer.
+// If the operation isn't successful, return 0 and an error.
+func (app *application) readMovieIDParam(r *http.Request) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.ParseInt(params.ByName("movie_id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid movie_id parameter")
+	}
+
+	return id, nil
+}
+
+// Retrieve the "credit_id" URL Parameter from the current request context and convert it to an integer.
+// If the operation isn't successful, return 0 and an error.
+func (app *application) readCreditIDParam(r *http.Request) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.ParseInt(params.ByName("credit_id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid credit_id parameter")
+	}
+
+	return id, nil
+}
+
+// Retrieve the "version" URL Parameter from the current request context and convert it to an integer.
+// If the operation isn't successful, return 0 and an error.
+func (app *application) readVersionParam(r *http.Request) (int32, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	version, err := strconv.ParseInt(params.ByName("version"), 10, 32)
+	if err != nil || version < 1 {
+		return 0, errors.New("invalid version parameter")
+	}
+
+	return int32(version), nil
+}
+
+// The movieETag() helper returns a strong entity tag of a movie response. The ID and version of the movie are
+// followed by a hash of the response, as the same version is sent with different fields, embedded relations,
+// translations and ratings. The responses of changes use the same tags, so they match a later GET.
+func movieETag(movie *data.Movie, data envelope, language string) (string, error) {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(append(js, language...))
+
+	return fmt.Sprintf(`"%d-%d-%x"`, movie.ID, movie.Version, sum[:8]), nil
+}
+
+// The writeMovieJSON() helper sends a changed movie together with its entity tag.
+func (app *application) writeMovieJSON(w http.ResponseWriter, status int, movie *data.Movie) error {
+	env := envelope{"movie": movie}
+
+	etag, err := movieETag(movie, env, "")
+	if err != nil {
+		return err
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag)
+
+	return app.writeJSON(w, status, env, headers)
+}
+
+// The weakETag() helper returns a weak entity tag computed over the JSON encoding of a response.
+func weakETag(data envelope) (string, error) {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(js)
+
+	return fmt.Sprintf(`W/"%x"`, sum[:16]), nil
+}
+
+// The movieETagMatch() helper reports whether one of the entity tags of a comma separated list from an If-Match
+// header belongs to the current version of a movie. The tags of all responses of the version match, as the
+// change is checked against the version. The wildcard "*" matches every entity tag.
+func movieETagMatch(header string, movie *data.Movie) bool {
+	prefix := fmt.Sprintf(`"%d-%d-`, movie.ID, movie.Version)
+
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+
+		if value == "*" || strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The etagMatchWeak() helper reports whether the given entity tag is part of a comma separated list of entity tags
+// from an If-None-Match header, using the weak comparison (the "W/" prefix is ignored).
+func etagMatchWeak(header string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+
+		if value == "*" || strings.TrimPrefix(value, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The notModified() helper sets the cache validators of a response. It reports whether the copy of the client
+// is still current according to the If-None-Match header, and sends a 304 Not Modified response in that case.
+// No Last-Modified header is sent, as ratings and embedded relations change the responses without changing
+// the modification time of the movies.
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	// The responses depend on the permissions of the user, so they may only be stored by private caches,
+	// and must be revalidated before they are used.
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("ETag", etag)
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatchWeak(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
+// The checkMovieVersion() helper checks the If-Match and X-Expected-Version headers of a request against the
+// current version of a movie. If a header doesn't match, an error response is sent and false is returned.
+func (app *application) checkMovieVersion(w http.ResponseWriter, r *http.Request, movie *data.Movie) bool {
+	// If the client sent an If-Match header, the change is only executed if it matches
+	// the current version of the movie.
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !movieETagMatch(ifMatch, movie) {
+			app.preconditionFailedResponse(w, r)
+			return false
+		}
+	}
+
+	// If the client sent an X-Expected-Version header, the change is only executed if it
+	// matches the current version of the movie.
+	if expectedVersion := r.Header.Get("X-Expected-Version"); expectedVersion != "" {
+		version, err := strconv.ParseInt(expectedVersion, 10, 32)
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("invalid X-Expected-Version header"))
+			return false
+		}
+
+		if int32(version) != movie.Version {
+			app.editConflictResponse(w, r)
+			return false
+		}
+	}
+
+	return true
+}
+
 type envelope map[string]any
 
 // Define a writeJSON() helper for sending responses. This takes the destination http.ResponseWriter,
@@ -140,6 +305,69 @@ func (app *application) readString(qs url.Values, key string, defaultValue strin
 	return s
 }
 
+// The readLanguages() helper returns the (lower-cased) preferred languages of the client in their order of
+// preference. The ?lang= query string parameter (a comma separated list) takes precedence over the
+// Accept-Language header. Invalid language tags and the wildcard are ignored.
+func (app *application) readLanguages(r *http.Request) []string {
+	type weighted struct {
+		language string
+		q        float64
+	}
+
+	var candidates []weighted
+
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		for _, language := range strings.Split(lang, ",") {
+			candidates = append(candidates, weighted{language: language, q: 1})
+		}
+	} else {
+		for _, value := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+			language, params, _ := strings.Cut(value, ";")
+			q := 1.0
+
+			if name, weight, found := strings.Cut(strings.TrimSpace(params), "="); found && strings.TrimSpace(name) == "q" {
+				var err error
+
+				q, err = strconv.ParseFloat(strings.TrimSpace(weight), 64)
+				if err != nil {
+					continue
+				}
+			}
+
+			candidates = append(candidates, weighted{language: language, q: q})
+		}
+	}
+
+	// Keep the order of languages with the same weight.
+	slices.SortStableFunc(candidates, func(a, b weighted) int {
+		return cmp.Compare(b.q, a.q)
+	})
+
+	languages := []string{}
+
+	for _, candidate := range candidates {
+		language := strings.ToLower(strings.TrimSpace(candidate.language))
+
+		if candidate.q > 0 && validator.Matches(language, data.LanguageRX) && !slices.Contains(languages, language) {
+			languages = append(languages, language)
+		}
+	}
+
+	return languages
+}
+
+// The readCSV() helper reads a string value from the query string and then splits it
+// into a slice on the comma character.
+func (app *application) readCSV(qs url.Values, key string, defaultValue []string) []string {
+	csv := qs.Get(key)
+
+	if csv == "" {
+		return defaultValue
+	}
+
+	return strings.Split(csv, ",")
+}
+
 func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
 	s := qs.Get(key)
 
@@ -155,6 +383,23 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// The readBool() helper reads a boolean value from the query string. If the value can't be converted
+// to a boolean, the error is recorded in the validator.
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
 // The background() helper accepts an function as a parameter which will be run as a background go routine.
 // It is used to recover any panic.
 func (app *application) background(fn func()) {
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/comfortliner/greenlight/internal/data"
+	"github.com/comfortliner/greenlight/internal/validator"
+)
+
+// Limit the size of an import request body to 64MB.
+const maxImportBytes = 64 << 20
+
+// movieRow struct represents a single row of an import together with its line number
+// and the errors which occurred while reading it.
+type movieRow struct {
+	line   int
+	movie  *data.Movie
+	errors map[string]string
+}
+
+// movieRowReader interface is implemented by the CSV and the NDJSON import formats.
+// Next() returns io.EOF if there are no more rows.
+type movieRowReader interface {
+	Next() (*movieRow, error)
+}
+
+// Add a importMoviesHandler for the "POST /v1/movies/import" endpoint.
+// The body is either a CSV file with a header line (title,year,runtime,genres and optional columns), or
+// newline delimited JSON objects. In "atomic" mode (default) all movies are inserted in a single
+// transaction, in "best_effort" mode every valid movie is inserted.
+func (app *application) importMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	mode := app.readString(r.URL.Query(), "mode", "atomic")
+	v.Check(validator.PermittedValue(mode, "atomic", "best_effort"), "mode", "must be atomic or best_effort")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Reading and inserting a large import can take much longer than the timeouts of the server.
+	rc := http.NewResponseController(w)
+
+	err := rc.SetReadDeadline(time.Now().Add(5 * time.Minute))
+	if err == nil {
+		err = rc.SetWriteDeadline(time.Now().Add(5 * time.Minute))
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	var rows movieRowReader
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		rows = newCSVMovieReader(r.Body)
+	case "application/x-ndjson":
+		rows = newNDJSONMovieReader(r.Body)
+	default:
+		app.unsupportedMediaTypeResponse(w, r)
+		return
+	}
+
+	var imp *data.MovieTx
+
+	if mode == "atomic" {
+		imp, err = app.models.Movies.BeginTx()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		defer imp.Rollback()
+	}
+
+	user := app.contextGetUser(r)
+
+	created := []int64{}
+	rowErrors := map[string]map[string]string{}
+
+	for {
+		row, err := rows.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if row.errors == nil {
+			v := validator.New()
+
+			if data.ValidateMovie(v, row.movie); !v.Valid() {
+				row.errors = v.Errors
+			}
+		}
+
+		if row.errors != nil {
+			rowErrors[strconv.Itoa(row.line)] = row.errors
+			continue
+		}
+
+		// In atomic mode it isn't worth inserting any further rows after the first error,
+		// but we keep on reading to report the errors of all rows.
+		switch {
+		case mode == "atomic" && len(rowErrors) > 0:
+			continue
+		case mode == "atomic":
+			err = imp.Insert(row.movie, user.ID)
+		default:
+			err = app.models.Movies.Insert(row.movie, user.ID)
+		}
+
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateExternalID):
+				rowErrors[strconv.Itoa(row.line)] = map[string]string{"external_ids": "must not be assigned to another movie"}
+				continue
+			default:
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		created = append(created, row.movie.ID)
+	}
+
+	if mode == "atomic" {
+		if len(rowErrors) > 0 {
+			app.failedRowValidationResponse(w, r, rowErrors)
+			return
+		}
+
+		err = imp.Commit()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	status := http.StatusCreated
+	if len(rowErrors) > 0 {
+		status = http.StatusOK
+	}
+
+	err = app.writeJSON(w, status, envelope{"created": created, "errors": rowErrors}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// csvMovieReader reads movies from a CSV file. The first line must contain the column names, the columns after
+// genres in the CSV export (status, release_date, original_language, ...) are optional. Lists are comma separated,
+// certifications and external IDs are "key:value" pairs, e.g. "DE:12,US:R".
+type csvMovieReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVMovieReader(r io.Reader) *csvMovieReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	return &csvMovieReader{reader: reader}
+}
+
+func (c *csvMovieReader) Next() (*movieRow, error) {
+	if c.columns == nil {
+		header, err := c.reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("body must not be empty")
+			}
+			return nil, err
+		}
+
+		c.columns = make(map[string]int)
+		for i, name := range header {
+			c.columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+
+		for _, name := range []string{"title", "year", "runtime", "genres"} {
+			if _, ok := c.columns[name]; !ok {
+				return nil, fmt.Errorf("csv header must contain the column %q", name)
+			}
+		}
+	}
+
+	record, err := c.reader.Read()
+	if err != nil {
+		var parseError *csv.ParseError
+
+		if errors.As(err, &parseError) {
+			return nil, fmt.Errorf("body contains badly-formed CSV (at line %d)", parseError.Line)
+		}
+		return nil, err
+	}
+
+	line, _ := c.reader.FieldPos(0)
+
+	field := func(name string) string {
+		if i, ok := c.columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	row := &movieRow{line: line, movie: &data.Movie{Title: field("title"), Status: data.MovieReleased}}
+	v := validator.New()
+
+	if year, err := strconv.ParseInt(field("year"), 10, 32); err == nil {
+		row.movie.Year = int32(year)
+	} else if field("year") != "" {
+		v.AddError("year", "must be an integer value")
+	}
+
+	if runtime, err := strconv.ParseInt(field("runtime"), 10, 32); err == nil {
+		row.movie.Runtime = int32(runtime)
+	} else if field("runtime") != "" {
+		v.AddError("runtime", "must be an integer value")
+	}
+
+	if genres := field("genres"); genres != "" {
+		row.movie.Genres = strings.Split(genres, ",")
+	}
+
+	if status := field("status"); status != "" {
+		row.movie.Status = status
+	}
+
+	if releaseDate := field("release_date"); releaseDate != "" {
+		if t, err := time.Parse("2006-01-02", releaseDate); err == nil {
+			row.movie.ReleaseDate = &data.Date{Time: t}
+		} else {
+			v.AddError("release_date", "must be formatted as YYYY-MM-DD")
+		}
+	}
+
+	row.movie.OriginalLanguage = field("original_language")
+	row.movie.Plot = field("plot")
+
+	if countries := field("production_countries"); countries != "" {
+		row.movie.ProductionCountries = strings.Split(countries, ",")
+	}
+
+	if languages := field("spoken_languages"); languages != "" {
+		row.movie.SpokenLanguages = strings.Split(languages, ",")
+	}
+
+	if certifications, ok := splitPairs(field("certifications")); ok {
+		row.movie.Certifications = certifications
+	} else {
+		v.AddError("certifications", `must be formatted as "country:certification" pairs, e.g. "DE:12,US:R"`)
+	}
+
+	if externalIDs, ok := splitPairs(field("external_ids")); ok {
+		row.movie.ExternalIDs = externalIDs
+	} else {
+		v.AddError("external_ids", `must be formatted as "source:id" pairs, e.g. "imdb:tt0137523"`)
+	}
+
+	if !v.Valid() {
+		row.errors = v.Errors
+	}
+
+	return row, nil
+}
+
+// The splitPairs() helper parses comma separated "key:value" pairs as written by joinPairs(). An empty string
+// is an empty map. It returns false if a pair has no key or value, or a key is repeated.
+func splitPairs(s string) (map[string]string, bool) {
+	if s == "" {
+		return nil, true
+	}
+
+	m := make(map[string]string)
+
+	for _, pair := range strings.Split(s, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || key == "" || value == "" {
+			return nil, false
+		}
+
+		if _, ok := m[key]; ok {
+			return nil, false
+		}
+
+		m[key] = value
+	}
+
+	return m, true
+}
+
+// ndjsonMovieReader reads movies from newline delimited JSON objects. Empty lines are skipped.
+type ndjsonMovieReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONMovieReader(r io.Reader) *ndjsonMovieReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1_048_576)
+
+	return &ndjsonMovieReader{scanner: scanner}
+}
+
+func (n *ndjsonMovieReader) Next() (*movieRow, error) {
+	for n.scanner.Scan() {
+		n.line++
+
+		line := strings.TrimSpace(n.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var input struct {
+			Title       string     `json:"title"`
+			Year        int32      `json:"year"`
+			Runtime     int32      `json:"runtime"`
+			Genres      []string   `json:"genres"`
+			Status      string     `json:"status"`
+			ReleaseDate *data.Date `json:"release_date"`
+
+			OriginalLanguage    string            `json:"original_language"`
+			ProductionCountries []string          `json:"production_countries"`
+			SpokenLanguages     []string          `json:"spoken_languages"`
+			Certifications      map[string]string `json:"certifications"`
+			Plot                string            `json:"plot"`
+			ExternalIDs         map[string]string `json:"external_ids"`
+		}
+
+		row := &movieRow{line: n.line}
+
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.DisallowUnknownFields()
+
+		err := dec.Decode(&input)
+		if err != nil {
+			row.errors = map[string]string{"json": fmt.Sprintf("line contains invalid JSON: %s", err)}
+			return row, nil
+		}
+
+		if input.Status == "" {
+			input.Status = data.MovieReleased
+		}
+
+		row.movie = &data.Movie{
+			Title:       input.Title,
+			Year:        input.Year,
+			Runtime:     input.Runtime,
+			Genres:      input.Genres,
+			Status:      input.Status,
+			ReleaseDate: input.ReleaseDate,
+
+			OriginalLanguage:    input.OriginalLanguage,
+			ProductionCountries: input.ProductionCountries,
+			SpokenLanguages:     input.SpokenLanguages,
+			Certifications:      input.Certifications,
+			Plot:                input.Plot,
+			ExternalIDs:         input.ExternalIDs,
+		}
+
+		return row, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
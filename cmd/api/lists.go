@@ -0,0 +1,378 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/comfortliner/greenlight/internal/data"
+	"github.com/comfortliner/greenlight/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Add a listMovieListsHandler for the "GET /v1/users/me/lists" endpoint.
+func (app *application) listMovieListsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	// Make sure the built-in watchlist exists.
+	_, err := app.models.MovieLists.GetWatchlist(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	lists, err := app.models.MovieLists.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"lists": lists}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a createMovieListHandler for the "POST /v1/users/me/lists" endpoint.
+func (app *application) createMovieListHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Visibility  string `json:"visibility"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	list := &data.MovieList{
+		UserID:      app.contextGetUser(r).ID,
+		Kind:        data.ListKindCustom,
+		Name:        input.Name,
+		Description: input.Description,
+		Visibility:  input.Visibility,
+	}
+
+	if list.Visibility == "" {
+		list.Visibility = data.ListVisibilityPrivate
+	}
+
+	v := validator.New()
+
+	if data.ValidateMovieList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.MovieLists.Insert(list)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a showMovieListHandler for the "GET /v1/users/me/lists/:id" endpoint.
+func (app *application) showMovieListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readMovieList(w, r)
+	if !ok {
+		return
+	}
+
+	app.writeMovieList(w, r, list)
+}
+
+// Add a showPublicMovieListHandler for the "GET /v1/lists/:slug" endpoint.
+// Public lists can be shared with everyone who knows their slug.
+func (app *application) showPublicMovieListHandler(w http.ResponseWriter, r *http.Request) {
+	slug := httprouter.ParamsFromContext(r.Context()).ByName("slug")
+
+	list, err := app.models.MovieLists.GetBySlug(slug)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeMovieList(w, r, list)
+}
+
+// Add a updateMovieListHandler for the "PATCH /v1/users/me/lists/:id" endpoint.
+func (app *application) updateMovieListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readMovieList(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Visibility  *string `json:"visibility"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	// Check for partial update
+	if input.Name != nil {
+		v.Check(!list.IsWatchlist(), "name", "the watchlist can't be renamed")
+		list.Name = *input.Name
+	}
+
+	if input.Description != nil {
+		list.Description = *input.Description
+	}
+
+	if input.Visibility != nil {
+		list.Visibility = *input.Visibility
+	}
+
+	if data.ValidateMovieList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.MovieLists.Update(list)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a deleteMovieListHandler for the "DELETE /v1/users/me/lists/:id" endpoint.
+func (app *application) deleteMovieListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readMovieList(w, r)
+	if !ok {
+		return
+	}
+
+	if list.IsWatchlist() {
+		app.badRequestResponse(w, r, errors.New("the watchlist can't be deleted"))
+		return
+	}
+
+	err := app.models.MovieLists.Delete(list.ID, list.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "list successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a putMovieListEntryHandler for the "PUT /v1/users/me/lists/:id/movies/:movie_id" endpoint.
+// New movies are added to the end of the list, for existing entries only the note is changed.
+func (app *application) putMovieListEntryHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readMovieList(w, r)
+	if !ok {
+		return
+	}
+
+	movieID, err := app.readMovieIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Note string `json:"note"`
+	}
+
+	// The body is optional, a movie can be added without a note.
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+
+	if data.ValidateMovieListNote(v, input.Note); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = app.models.Movies.Get(movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.MovieLists.UpsertEntry(list.ID, movieID, input.Note)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeMovieList(w, r, list)
+}
+
+// Add a deleteMovieListEntryHandler for the "DELETE /v1/users/me/lists/:id/movies/:movie_id" endpoint.
+func (app *application) deleteMovieListEntryHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readMovieList(w, r)
+	if !ok {
+		return
+	}
+
+	movieID, err := app.readMovieIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.MovieLists.DeleteEntry(list.ID, movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeMovieList(w, r, list)
+}
+
+// Add a reorderMovieListHandler for the "PUT /v1/users/me/lists/:id/order" endpoint.
+// The body contains the movie IDs of the list in their new order.
+func (app *application) reorderMovieListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readMovieList(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		MovieIDs []int64 `json:"movie_ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entries, err := app.models.MovieLists.GetEntries(list.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	onList := make(map[int64]bool, len(entries))
+	for _, entry := range entries {
+		onList[entry.MovieID] = true
+	}
+
+	v := validator.New()
+
+	v.Check(input.MovieIDs != nil, "movie_ids", "must be provided")
+	v.Check(validator.Unique(input.MovieIDs), "movie_ids", "must not contain duplicate values")
+
+	for _, id := range input.MovieIDs {
+		v.Check(onList[id], "movie_ids", "must only contain movies of the list")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.MovieLists.Reorder(list.ID, input.MovieIDs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeMovieList(w, r, list)
+}
+
+// The readMovieList() helper returns the list of the current user given by the "id" URL parameter.
+// The built-in watchlist can be addressed with the id "watchlist". If the list can't be found,
+// an error response is sent and false is returned.
+func (app *application) readMovieList(w http.ResponseWriter, r *http.Request) (*data.MovieList, bool) {
+	user := app.contextGetUser(r)
+
+	var list *data.MovieList
+	var err error
+
+	if httprouter.ParamsFromContext(r.Context()).ByName("id") == data.ListKindWatchlist {
+		list, err = app.models.MovieLists.GetWatchlist(user.ID)
+	} else {
+		var id int64
+
+		id, err = app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return nil, false
+		}
+
+		list, err = app.models.MovieLists.Get(id, user.ID)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return list, true
+}
+
+// The writeMovieList() helper sends a list together with its entries to the client.
+func (app *application) writeMovieList(w http.ResponseWriter, r *http.Request, list *data.MovieList) {
+	entries, err := app.models.MovieLists.GetEntries(list.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	list.Entries = entries
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/comfortliner/greenlight/internal/data"
 	"github.com/comfortliner/greenlight/internal/mailer"
+	"github.com/comfortliner/greenlight/internal/storage"
 	"github.com/comfortliner/greenlight/internal/vcs"
 
 	"github.com/alexedwards/scs/mssqlstore"
@@ -34,7 +35,12 @@ type config struct {
 		maxIdleConns int
 		maxIdleTime  string
 	}
-	env  string
+	env    string
+	movies struct {
+		trashRetention    time.Duration
+		similarityRefresh time.Duration
+		statsTTL          time.Duration
+	}
 	name string
 	port int
 	smtp struct {
@@ -44,6 +50,9 @@ type config struct {
 		password string
 		sender   string
 	}
+	storage struct {
+		dir string
+	}
 	version string
 }
 
@@ -57,6 +66,9 @@ type application struct {
 	templateCache  map[string]*template.Template
 	formDecoder    *form.Decoder
 	sessionManager *scs.SessionManager
+	storage        storage.Storage
+	similarity     similarityCache
+	stats          statsCache
 }
 
 func main() {
@@ -76,12 +88,18 @@ func main() {
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "SQLServer max idle connections")
 	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", "15m", "SQLServer max connection idle time")
 
+	flag.DurationVar(&cfg.movies.trashRetention, "movies-trash-retention", 30*24*time.Hour, "Retention period of deleted movies before they can be purged")
+	flag.DurationVar(&cfg.movies.similarityRefresh, "movies-similarity-refresh", 10*time.Minute, "Age of the similarity index after which it is rebuilt in the background")
+	flag.DurationVar(&cfg.movies.statsTTL, "movies-stats-ttl", 5*time.Minute, "Time to live of the cached movie statistics")
+
 	flag.StringVar(&cfg.smtp.host, "smtp-host", "smtp.mailtrap.io", "SMTP host")
 	flag.IntVar(&cfg.smtp.port, "smtp-port", 25, "SMTP port")
 	flag.StringVar(&cfg.smtp.username, "smtp-username", "d272ee6f9eb15f", "SMTP username")
 	flag.StringVar(&cfg.smtp.password, "smtp-password", "2f762fd7bf31fc", "SMTP password")
 	flag.StringVar(&cfg.smtp.sender, "smtp-sender", "API <no-reply@api.net>", "SMTP sender")
 
+	flag.StringVar(&cfg.storage.dir, "storage-dir", "./storage", "Directory for uploaded files (e.g. movie posters)")
+
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space separated)",
 		func(val string) error {
 			cfg.cors.trustedOrigins = strings.Fields(val)
@@ -131,6 +149,13 @@ func main() {
 	sessionManager.Store = mssqlstore.New(db)
 	sessionManager.Lifetime = 12 * time.Hour
 
+	// Initialize the storage of uploaded files.
+	store, err := storage.NewLocal(cfg.storage.dir)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	// Declare an instance of the application struct.
 	app := &application{
 		config:         cfg,
@@ -140,6 +165,7 @@ func main() {
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
+		storage:        store,
 	}
 
 	err = app.serve()
@@ -1,20 +1,38 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
 
 	"github.com/comfortliner/greenlight/internal/data"
+	"github.com/comfortliner/greenlight/internal/jsonpatch"
 	"github.com/comfortliner/greenlight/internal/validator"
+	"github.com/julienschmidt/httprouter"
 )
 
 // Add a createMovieHandler for the "POST /v1/movies" endpoint.
+// A movie which is a likely duplicate of an existing movie is only added with ?force=true.
 func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title   string `json:"title"`
-		Year    int32  `json:"year"`
-		Runtime int32  `json:"runtime"`
+		Title       string     `json:"title"`
+		Year        int32      `json:"year"`
+		Runtime     int32      `json:"runtime"`
+		Genres      []string   `json:"genres"`
+		Status      string     `json:"status"`
+		ReleaseDate *data.Date `json:"release_date"`
+
+		OriginalLanguage    string            `json:"original_language"`
+		ProductionCountries []string          `json:"production_countries"`
+		SpokenLanguages     []string          `json:"spoken_languages"`
+		Certifications      map[string]string `json:"certifications"`
+		Plot                string            `json:"plot"`
+		ExternalIDs         map[string]string `json:"external_ids"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -23,25 +41,61 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Movies are released unless another status is given.
+	if input.Status == "" {
+		input.Status = data.MovieReleased
+	}
+
 	// Copy the values from the input struct to a new Movie struct.
 	movie := &data.Movie{
-		Title:   input.Title,
-		Year:    input.Year,
-		Runtime: input.Runtime,
+		Title:       input.Title,
+		Year:        input.Year,
+		Runtime:     input.Runtime,
+		Genres:      input.Genres,
+		Status:      input.Status,
+		ReleaseDate: input.ReleaseDate,
+
+		OriginalLanguage:    input.OriginalLanguage,
+		ProductionCountries: input.ProductionCountries,
+		SpokenLanguages:     input.SpokenLanguages,
+		Certifications:      input.Certifications,
+		Plot:                input.Plot,
+		ExternalIDs:         input.ExternalIDs,
 	}
 
 	// Initialize a new Validator instance.
 	v := validator.New()
 
+	force := app.readBool(r.URL.Query(), "force", false, v)
+
 	// Use the Valid() method to see if any of the checks failed.
 	if data.ValidateMovie(v, movie); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Movies.Insert(movie)
+	if !force {
+		duplicates, err := app.models.Movies.FindDuplicates(movie)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if len(duplicates) > 0 {
+			app.duplicateMovieResponse(w, r, duplicates)
+			return
+		}
+	}
+
+	err = app.models.Movies.Insert(movie, app.contextGetUser(r).ID)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			v.AddError("external_ids", "must not be assigned to another movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -55,6 +109,8 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 }
 
 // Add a showMovieHandler for the "GET /v1/movies/:id" endpoint.
+// The fields can be limited with ?fields=id,title and related resources embedded with ?include=credits.
+// A movie which has been merged into another movie redirects to it.
 func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -62,32 +118,73 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	v := validator.New()
+
+	fields, includes := app.readMovieFields(r.URL.Query(), v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.GetFields(id, fields, app.readLanguages(r))
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
-			app.notFoundResponse(w, r)
+			app.movieNotFoundResponse(w, r, id)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	err = app.embedMovieRelations([]*data.Movie{movie}, includes)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
 	}
-}
 
-// Add a updateMovieHandler for the "PATCH /v1/movies/:id" endpoint.
-func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.readIDParam(r)
+	env := envelope{"movie": movieResponse(movie, fields)}
+
+	etag, err := movieETag(movie, env, movie.Language)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The title is translated to the preferred language of the client. The Vary header is added to,
+	// as the middleware has already set it for the Authorization and Origin headers.
+	w.Header().Add("Vary", "Accept-Language")
+
+	if app.notModified(w, r, etag) {
+		return
+	}
+
+	headers := make(http.Header)
+
+	if movie.Language != "" {
+		headers.Set("Content-Language", movie.Language)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, headers)
 	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a showMovieByExternalIDHandler for the "GET /v1/movies/by-external-id/:source/:id" endpoint.
+// It looks a movie up by its ID in an external database, e.g. /v1/movies/by-external-id/imdb/tt0137523.
+func (app *application) showMovieByExternalIDHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	source, externalID := params.ByName("source"), params.ByName("id")
+
+	if _, ok := data.ExternalIDSources[source]; !ok {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	movie, err := app.models.Movies.GetByExternalID(source, externalID, app.readLanguages(r))
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -98,31 +195,293 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	var input struct {
-		Title   *string `json:"title"`
-		Year    *int32  `json:"year"`
-		Runtime *int32  `json:"runtime"`
+	w.Header().Add("Vary", "Accept-Language")
+
+	headers := make(http.Header)
+	headers.Set("Content-Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
+
+	if movie.Language != "" {
+		headers.Set("Content-Language", movie.Language)
 	}
 
-	err = app.readJSON(w, r, &input)
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a replaceMovieHandler for the "PUT /v1/movies/:id" endpoint.
+// All fields of the movie are replaced, missing fields fail the validation.
+func (app *application) replaceMovieHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := app.readMovieForUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	var input movieDocument
+
+	err := app.readJSON(w, r, &input)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	// Check for partial update
-	if input.Title != nil {
-		movie.Title = *input.Title
+	movie.Title = input.Title
+	movie.Year = input.Year
+	movie.Runtime = input.Runtime
+	movie.Genres = input.Genres
+	movie.Status = input.Status
+	movie.ReleaseDate = input.ReleaseDate
+	movie.OriginalLanguage = input.OriginalLanguage
+	movie.ProductionCountries = input.ProductionCountries
+	movie.SpokenLanguages = input.SpokenLanguages
+	movie.Certifications = input.Certifications
+	movie.Plot = input.Plot
+	movie.ExternalIDs = input.ExternalIDs
+
+	app.saveMovie(w, r, movie)
+}
+
+// Add a updateMovieHandler for the "PATCH /v1/movies/:id" endpoint.
+// The Content-Type selects the format of the patch: a JSON Merge Patch (application/merge-patch+json),
+// a JSON Patch (application/json-patch+json), or otherwise a JSON object with the changed fields.
+func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := app.readMovieForUpdate(w, r)
+	if !ok {
+		return
 	}
 
-	if input.Year != nil {
-		movie.Year = *input.Year
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "application/merge-patch+json", "application/json-patch+json":
+		if !app.patchMovie(w, r, movie, mediaType) {
+			return
+		}
+	default:
+		var input struct {
+			Title       *string    `json:"title"`
+			Year        *int32     `json:"year"`
+			Runtime     *int32     `json:"runtime"`
+			Genres      []string   `json:"genres"`
+			Status      *string    `json:"status"`
+			ReleaseDate *data.Date `json:"release_date"`
+
+			OriginalLanguage    *string           `json:"original_language"`
+			ProductionCountries []string          `json:"production_countries"`
+			SpokenLanguages     []string          `json:"spoken_languages"`
+			Certifications      map[string]string `json:"certifications"`
+			Plot                *string           `json:"plot"`
+			ExternalIDs         map[string]string `json:"external_ids"`
+		}
+
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		// Check for partial update
+		if input.Title != nil {
+			movie.Title = *input.Title
+		}
+
+		if input.Year != nil {
+			movie.Year = *input.Year
+		}
+
+		if input.Runtime != nil {
+			movie.Runtime = *input.Runtime
+		}
+
+		if input.Genres != nil {
+			movie.Genres = input.Genres
+		}
+
+		if input.Status != nil {
+			movie.Status = *input.Status
+		}
+
+		if input.ReleaseDate != nil {
+			movie.ReleaseDate = input.ReleaseDate
+		}
+
+		if input.OriginalLanguage != nil {
+			movie.OriginalLanguage = *input.OriginalLanguage
+		}
+
+		if input.ProductionCountries != nil {
+			movie.ProductionCountries = input.ProductionCountries
+		}
+
+		if input.SpokenLanguages != nil {
+			movie.SpokenLanguages = input.SpokenLanguages
+		}
+
+		if input.Certifications != nil {
+			movie.Certifications = input.Certifications
+		}
+
+		if input.Plot != nil {
+			movie.Plot = *input.Plot
+		}
+
+		if input.ExternalIDs != nil {
+			movie.ExternalIDs = input.ExternalIDs
+		}
 	}
 
-	if input.Runtime != nil {
-		movie.Runtime = *input.Runtime
+	app.saveMovie(w, r, movie)
+}
+
+// movieDocument struct is the document of a movie which is replaced by PUT and patched by PATCH.
+type movieDocument struct {
+	Title       string     `json:"title"`
+	Year        int32      `json:"year"`
+	Runtime     int32      `json:"runtime"`
+	Genres      []string   `json:"genres"`
+	Status      string     `json:"status"`
+	ReleaseDate *data.Date `json:"release_date"`
+
+	OriginalLanguage    string            `json:"original_language"`
+	ProductionCountries []string          `json:"production_countries"`
+	SpokenLanguages     []string          `json:"spoken_languages"`
+	Certifications      map[string]string `json:"certifications"`
+	Plot                string            `json:"plot"`
+	ExternalIDs         map[string]string `json:"external_ids"`
+}
+
+// The patchMovie() helper applies a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) to the document of
+// a movie. The patch may only change the fields of the movieDocument, the id and version can be used in "test"
+// operations. If the patch can't be applied, an error response is sent and false is returned.
+func (app *application) patchMovie(w http.ResponseWriter, r *http.Request, movie *data.Movie, mediaType string) bool {
+	doc, err := json.Marshal(struct {
+		ID int64 `json:"id"`
+		movieDocument
+		Version int32 `json:"version"`
+	}{movie.ID, movieDocument{
+		Title:               movie.Title,
+		Year:                movie.Year,
+		Runtime:             movie.Runtime,
+		Genres:              movie.Genres,
+		Status:              movie.Status,
+		ReleaseDate:         movie.ReleaseDate,
+		OriginalLanguage:    movie.OriginalLanguage,
+		ProductionCountries: movie.ProductionCountries,
+		SpokenLanguages:     movie.SpokenLanguages,
+		Certifications:      movie.Certifications,
+		Plot:                movie.Plot,
+		ExternalIDs:         movie.ExternalIDs,
+	}, movie.Version})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
 	}
 
+	var patched []byte
+
+	if mediaType == "application/merge-patch+json" {
+		var patch json.RawMessage
+
+		err = app.readJSON(w, r, &patch)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return false
+		}
+
+		patched, err = jsonpatch.Merge(doc, patch)
+	} else {
+		var operations []jsonpatch.Operation
+
+		err = app.readJSON(w, r, &operations)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return false
+		}
+
+		patched, err = jsonpatch.Apply(doc, operations)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			app.errorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+		}
+		return false
+	}
+
+	var result struct {
+		ID *int64 `json:"id"`
+		movieDocument
+		Version *int32 `json:"version"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(&result)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("the patched movie is invalid: %s", err))
+		return false
+	}
+
+	v := validator.New()
+
+	v.Check(result.ID != nil && *result.ID == movie.ID, "id", "must not be changed")
+	v.Check(result.Version != nil && *result.Version == movie.Version, "version", "must not be changed")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return false
+	}
+
+	movie.Title = result.Title
+	movie.Year = result.Year
+	movie.Runtime = result.Runtime
+	movie.Genres = result.Genres
+	movie.Status = result.Status
+	movie.ReleaseDate = result.ReleaseDate
+	movie.OriginalLanguage = result.OriginalLanguage
+	movie.ProductionCountries = result.ProductionCountries
+	movie.SpokenLanguages = result.SpokenLanguages
+	movie.Certifications = result.Certifications
+	movie.Plot = result.Plot
+	movie.ExternalIDs = result.ExternalIDs
+
+	return true
+}
+
+// The readMovieForUpdate() helper reads the movie of a PUT or PATCH request and checks its version against
+// the headers of the request. If the movie can't be updated, an error response is sent and false is returned.
+func (app *application) readMovieForUpdate(w http.ResponseWriter, r *http.Request) (*data.Movie, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	movie, err := app.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if !app.checkMovieVersion(w, r, movie) {
+		return nil, false
+	}
+
+	return movie, true
+}
+
+// The saveMovie() helper validates and stores the changed movie of a PUT or PATCH request and sends
+// the movie to the client.
+func (app *application) saveMovie(w http.ResponseWriter, r *http.Request, movie *data.Movie) {
 	// Initialize a new Validator instance.
 	v := validator.New()
 
@@ -132,18 +491,21 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Movies.Update(movie)
+	err := app.models.Movies.Update(movie, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			v.AddError("external_ids", "must not be assigned to another movie")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	err = app.writeMovieJSON(w, http.StatusOK, movie)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -168,16 +530,90 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie successfully deleted"}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie successfully moved to the trash"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// Add a mergeMovieHandler for the "POST /v1/movies/:id/merge" endpoint.
+// The movie is merged as duplicate into the target movie, which is returned.
+func (app *application) mergeMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		TargetID int64 `json:"target_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.TargetID > 0, "target_id", "must be provided")
+	v.Check(input.TargetID != id, "target_id", "must not be the merged movie")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Movies.Merge(id, input.TargetID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movie, err := app.models.Movies.Get(input.TargetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeMovieJSON(w, http.StatusOK, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The movieNotFoundResponse() method sends a redirect to the target movie if the requested movie has been
+// merged into another movie, and a 404 Not Found response otherwise.
+func (app *application) movieNotFoundResponse(w http.ResponseWriter, r *http.Request, id int64) {
+	targetID, err := app.models.Movies.GetMergeTarget(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.movieMergedResponse(w, r, targetID)
+}
+
 // Add a listMoviesHandler for the "GET /v1/movies" endpoint.
 func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title string
+		data.MovieQuery
 		data.Filters
 	}
 
@@ -186,26 +622,283 @@ func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request
 	qs := r.URL.Query()
 
 	input.Title = app.readString(qs, "title", "")
+	input.Genres = app.readCSV(qs, "genres", []string{})
+	input.PersonID = int64(app.readInt(qs, "person", 0, v))
+	input.DirectorID = int64(app.readInt(qs, "director", 0, v))
+
+	var includes []string
+	input.Fields, includes = app.readMovieFields(qs, v)
+	input.Languages = app.readLanguages(r)
+
+	if filter := app.readString(qs, "filter", ""); filter != "" {
+		expression, err := data.ParseExpression(filter, data.MovieFilterFields)
+		if err != nil {
+			v.AddError("filter", err.Error())
+		}
+
+		input.Filter = expression
+	}
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 
 	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "rating", "relevance", "-id", "-title", "-year", "-runtime", "-rating"}
+	input.Filters.TextColumns = []string{"title"}
+
+	// The cursor parameter switches to keyset pagination, an empty cursor requests the first page.
+	if qs.Has("cursor") {
+		cursor, err := data.DecodeCursor(qs.Get("cursor"))
+		if err != nil {
+			v.AddError("cursor", "must be a cursor returned by a previous request")
+		}
+
+		v.Check(!qs.Has("page"), "page", "must not be used together with cursor")
+
+		input.Filters.Cursor = cursor
+	}
+
+	format := app.exportFormat(r)
+	v.Check(validator.PermittedValue(format, "json", "csv", "ndjson"), "format", "must be json, csv or ndjson")
+	v.Check(format == "json" || (input.Fields == nil && len(includes) == 0), "fields", "can only be used with the json format")
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Exports contain all matching movies and are streamed directly to the client.
+	if format != "json" {
+		app.exportMovies(w, r, format, input.MovieQuery, input.Filters)
+		return
+	}
+
+	movies, metadata, err := app.models.Movies.GetAll(input.MovieQuery, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.embedMovieRelations(movies, includes)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := make([]any, len(movies))
+	for i, movie := range movies {
+		response[i] = movieResponse(movie, input.Fields)
+	}
+
+	env := envelope{"movies": response, "metadata": metadata}
+
+	// The list has no single modification time, as movies can also leave it (e.g. if they are deleted),
+	// so it is only validated by a weak entity tag over the whole page.
+	etag, err := weakETag(env)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	if app.notModified(w, r, etag) {
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a listTrashedMoviesHandler for the "GET /v1/movies/trash" endpoint.
+func (app *application) listTrashedMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "-deleted_at")
+	input.Filters.SortSafelist = []string{"id", "title", "deleted_at", "-id", "-title", "-deleted_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, metadata, err := app.models.Movies.GetTrash(input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a listUpcomingMoviesHandler for the "GET /v1/movies/upcoming" endpoint.
+// The announced movies are listed by their release date.
+func (app *application) listUpcomingMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = "release_date"
+	input.Filters.SortSafelist = []string{"release_date"}
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	movies, err := app.models.Movies.GetAll(input.Title, input.Filters)
+	movies, metadata, err := app.models.Movies.GetUpcoming(input.Filters, app.readLanguages(r))
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies}, nil)
+	w.Header().Add("Vary", "Accept-Language")
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// Add a restoreMovieHandler for the "POST /v1/movies/:id/restore" endpoint.
+func (app *application) restoreMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.models.Movies.Restore(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeMovieJSON(w, http.StatusOK, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a purgeTrashedMoviesHandler for the "DELETE /v1/movies/trash" endpoint.
+// Only movies which have been in the trash for longer than the configured retention period are removed.
+// The posters of the removed movies are deleted from the storage in the background.
+func (app *application) purgeTrashedMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	purged, err := app.models.Movies.Purge(app.config.movies.trashRetention)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(purged) > 0 {
+		app.background(func() {
+			for _, id := range purged {
+				app.deletePosters(id)
+			}
+		})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"purged": len(purged)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The readMovieFields() helper reads the sparse fieldset (?fields=) and the related resources to embed
+// (?include=) from the query string. The fieldset is nil if all fields are requested.
+func (app *application) readMovieFields(qs url.Values, v *validator.Validator) ([]string, []string) {
+	fields := app.readCSV(qs, "fields", nil)
+	includes := app.readCSV(qs, "include", []string{})
+
+	for _, field := range fields {
+		v.Check(validator.PermittedValue(field, data.MovieFields...), "fields", fmt.Sprintf("invalid field %q", field))
+	}
+
+	for _, include := range includes {
+		v.Check(validator.PermittedValue(include, data.MovieIncludes...), "include", fmt.Sprintf("invalid include %q", include))
+	}
+
+	// The genres are stored with the movie, so they are embedded by adding them to the fieldset.
+	if fields != nil && slices.Contains(includes, "genres") && !slices.Contains(fields, "genres") {
+		fields = append(fields, "genres")
+	}
+
+	return fields, includes
+}
+
+// The embedMovieRelations() helper loads the requested related resources of the movies and embeds them.
+// The resources of all movies are read with a single query per resource.
+func (app *application) embedMovieRelations(movies []*data.Movie, includes []string) error {
+	if len(movies) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	if slices.Contains(includes, "credits") {
+		credits, err := app.models.Credits.GetAllForMovies(ids)
+		if err != nil {
+			return err
+		}
+
+		for _, movie := range movies {
+			movie.Credits = credits[movie.ID]
+		}
+	}
+
+	if slices.Contains(includes, "ratings") {
+		summaries, err := app.models.Ratings.GetSummaries(ids)
+		if err != nil {
+			return err
+		}
+
+		for _, movie := range movies {
+			movie.Ratings = summaries[movie.ID]
+		}
+	}
+
+	return nil
+}
+
+// The movieResponse() helper returns the movie to marshal, limited to the fields of the sparse fieldset
+// if fields isn't nil. Embedded resources are always marshalled, even if they are empty.
+func movieResponse(movie *data.Movie, fields []string) any {
+	if fields == nil {
+		if movie.Credits == nil && movie.Ratings == nil {
+			return movie
+		}
+
+		fields = data.MovieFields
+	}
+
+	return movie.Project(fields)
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/comfortliner/greenlight/internal/data"
+	"github.com/comfortliner/greenlight/internal/storage"
+	"github.com/comfortliner/greenlight/internal/validator"
+)
+
+const (
+	// Limit the size of a poster upload to 10MB.
+	maxPosterBytes = 10 << 20
+
+	// Limit the dimensions of a poster, as the decoded image is held in memory.
+	maxPosterDimension = 4000
+)
+
+// posterSizes holds the widths of the thumbnails which are generated for every poster.
+var posterSizes = map[string]int{
+	"small":  185,
+	"medium": 342,
+	"large":  780,
+}
+
+// errUnsupportedPoster is returned by readPoster() if the upload isn't a JPEG or PNG image.
+var errUnsupportedPoster = errors.New("unsupported poster content type")
+
+// Add a uploadMoviePosterHandler for the "PUT /v1/movies/:id/poster" endpoint.
+// The poster is either uploaded as multipart form (with a "poster" part), or as raw JPEG or PNG body.
+// The original image is stored together with thumbnails of all posterSizes.
+func (app *application) uploadMoviePosterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Uploading a large image and generating the thumbnails can take longer than the timeouts of the server.
+	rc := http.NewResponseController(w)
+
+	err = rc.SetReadDeadline(time.Now().Add(time.Minute))
+	if err == nil {
+		err = rc.SetWriteDeadline(time.Now().Add(time.Minute))
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPosterBytes)
+
+	poster, err := app.readPoster(r)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.Is(err, errUnsupportedPoster):
+			app.unsupportedMediaTypeResponse(w, r)
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxPosterBytes))
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+
+	// Don't trust the declared content type, but check the content itself.
+	contentType := http.DetectContentType(poster)
+	v.Check(validator.PermittedValue(contentType, "image/jpeg", "image/png"), "poster", "must be a JPEG or PNG image")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Check the dimensions before decoding the whole image.
+	config, _, err := image.DecodeConfig(bytes.NewReader(poster))
+	if err == nil {
+		v.Check(config.Width >= 1 && config.Height >= 1, "poster", "must not be empty")
+		v.Check(config.Width <= maxPosterDimension && config.Height <= maxPosterDimension, "poster", fmt.Sprintf("must not be larger than %dx%d pixels", maxPosterDimension, maxPosterDimension))
+	} else {
+		v.AddError("poster", "must be a valid image")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(poster))
+	if err != nil {
+		v.AddError("poster", "must be a valid image")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// All thumbnails are generated before anything is stored, so a failure doesn't leave the new original
+	// next to the thumbnails of the previous poster.
+	src := flatten(img)
+
+	files := map[string][]byte{"original": poster}
+
+	for size, width := range posterSizes {
+		var buf bytes.Buffer
+
+		err = jpeg.Encode(&buf, thumbnail(src, width), &jpeg.Options{Quality: 85})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		files[size] = buf.Bytes()
+	}
+
+	for size, file := range files {
+		err = app.storage.Put(posterKey(movie.ID, size), bytes.NewReader(file))
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.models.Movies.SetPoster(movie, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a showMoviePosterHandler for the "GET /v1/movies/:id/poster" endpoint.
+// The size is one of the posterSizes or "original" (?size=, default "medium").
+func (app *application) showMoviePosterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	size := app.readString(r.URL.Query(), "size", "medium")
+	_, ok := posterSizes[size]
+	v.Check(ok || size == "original", "size", "must be small, medium, large or original")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.GetFields(id, []string{"poster_url"}, nil)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if movie.PosterUpdatedAt == nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	key := posterKey(movie.ID, size)
+
+	file, err := app.storage.Open(key)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	defer file.Close()
+
+	// The poster URL changes with every upload, so the poster can be cached for a long time.
+	// ServeContent() answers conditional requests with the ETag and the modification time.
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("ETag", fmt.Sprintf(`"%d-%s"`, movie.PosterUpdatedAt.UnixMilli(), size))
+
+	http.ServeContent(w, r, key, *movie.PosterUpdatedAt, file)
+}
+
+// The readPoster() helper reads the uploaded image from a multipart form or a raw request body.
+func (app *application) readPoster(r *http.Request) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "image/jpeg", "image/png":
+		return io.ReadAll(r.Body)
+	case "multipart/form-data":
+		mr, err := r.MultipartReader()
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil, errors.New(`body must contain a "poster" part`)
+				}
+				return nil, err
+			}
+
+			if part.FormName() == "poster" {
+				return io.ReadAll(part)
+			}
+		}
+	default:
+		return nil, errUnsupportedPoster
+	}
+}
+
+// The posterKey() function returns the storage key of a poster size. The original image is stored
+// without extension, as it can be a JPEG or a PNG image.
+func posterKey(movieID int64, size string) string {
+	if size == "original" {
+		return fmt.Sprintf("posters/%d/original", movieID)
+	}
+
+	return fmt.Sprintf("posters/%d/%s.jpg", movieID, size)
+}
+
+// The deletePosters() helper removes the original poster and all thumbnails of a movie from the storage.
+// The movie is already gone at this point, so errors are only logged.
+func (app *application) deletePosters(movieID int64) {
+	keys := []string{posterKey(movieID, "original")}
+	for size := range posterSizes {
+		keys = append(keys, posterKey(movieID, size))
+	}
+
+	for _, key := range keys {
+		err := app.storage.Delete(key)
+		if err != nil {
+			app.logger.Error(err.Error(), "key", key)
+		}
+	}
+}
+
+// The flatten() function draws an image onto a white background, as the thumbnails are stored as JPEG
+// images without transparency.
+func flatten(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+
+	return dst
+}
+
+// The thumbnail() function scales an image down to the given width, keeping its aspect ratio.
+// Every pixel of the thumbnail is the average of the pixels of the image it covers. Images which
+// are smaller than the width aren't scaled up.
+func thumbnail(src *image.RGBA, width int) *image.RGBA {
+	bounds := src.Bounds()
+
+	if bounds.Dx() <= width {
+		width = bounds.Dx()
+	}
+
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y*bounds.Dy()/height, max((y+1)*bounds.Dy()/height, y*bounds.Dy()/height+1)
+
+		for x := 0; x < width; x++ {
+			x0, x1 := x*bounds.Dx()/width, max((x+1)*bounds.Dx()/width, x*bounds.Dx()/width+1)
+
+			var sum [4]int
+
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					i := src.PixOffset(sx, sy)
+					for c := 0; c < 4; c++ {
+						sum[c] += int(src.Pix[i+c])
+					}
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			i := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/comfortliner/greenlight/internal/data"
+	"github.com/comfortliner/greenlight/internal/validator"
+)
+
+// Add a listMovieRevisionsHandler for the "GET /v1/movies/:id/revisions" endpoint.
+func (app *application) listMovieRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "-version")
+	input.Filters.SortSafelist = []string{"version", "-version"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Make sure the movie exists (and isn't in the trash).
+	_, err = app.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	revisions, metadata, err := app.models.MovieRevisions.GetAllForMovie(id, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a showMovieRevisionHandler for the "GET /v1/movies/:id/revisions/:version" endpoint.
+// The response contains the revision and the fields which differ from the current version of the movie.
+func (app *application) showMovieRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	movie, revision, ok := app.readMovieRevision(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"revision": revision, "diff": revision.Diff(movie)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a revertMovieHandler for the "POST /v1/movies/:id/revert/:version" endpoint.
+// Reverting doesn't rewrite the history, the values of the revision are stored as a new version.
+func (app *application) revertMovieHandler(w http.ResponseWriter, r *http.Request) {
+	movie, revision, ok := app.readMovieRevision(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.checkMovieVersion(w, r, movie) {
+		return
+	}
+
+	revision.Apply(movie)
+
+	// The rules may have changed since the revision has been stored, so the reverted movie is validated again.
+	v := validator.New()
+
+	if data.ValidateMovie(v, movie); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.models.Movies.Update(movie, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			v.AddError("external_ids", "must not be assigned to another movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeMovieJSON(w, http.StatusOK, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The readMovieRevision() helper reads the current movie and the requested revision from the
+// "id" and "version" URL parameters. If one of them can't be found, an error response is sent
+// and false is returned.
+func (app *application) readMovieRevision(w http.ResponseWriter, r *http.Request) (*data.Movie, *data.MovieRevision, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, nil, false
+	}
+
+	version, err := app.readVersionParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, nil, false
+	}
+
+	movie, err := app.models.Movies.Get(id)
+	if err == nil {
+		var revision *data.MovieRevision
+
+		revision, err = app.models.MovieRevisions.Get(id, version)
+		if err == nil {
+			return movie, revision, true
+		}
+	}
+
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		app.notFoundResponse(w, r)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+
+	return nil, nil, false
+}
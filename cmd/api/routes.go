@@ -11,6 +11,11 @@ func (app *application) routes() http.Handler {
 	// Initialize a new httprouter router instance.
 	router := httprouter.New()
 
+	// httprouter doesn't allow a static path segment and a wildcard at the same position (e.g.
+	// "/v1/movies/trash" and "/v1/movies/:id"), so static routes like these are registered with a
+	// second router instance, which is looked up first.
+	staticRouter := httprouter.New()
+
 	router.NotFound = http.HandlerFunc(app.notFoundResponse)
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
 
@@ -53,14 +58,87 @@ func (app *application) routes() http.Handler {
 	// movies
 	router.HandlerFunc(http.MethodGet, "/v1/movies", app.requirePermission("movies:read", app.listMoviesHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/movies", app.requirePermission("movies:write", app.createMovieHandler))
+	staticRouter.HandlerFunc(http.MethodPost, "/v1/movies/import", app.requirePermission("movies:write", app.importMoviesHandler))
+	staticRouter.HandlerFunc(http.MethodPost, "/v1/movies/batch", app.requirePermission("movies:write", app.batchMoviesHandler))
+	staticRouter.HandlerFunc(http.MethodGet, "/v1/movies/upcoming", app.requirePermission("movies:read", app.listUpcomingMoviesHandler))
+	staticRouter.HandlerFunc(http.MethodGet, "/v1/movies/by-external-id/:source/:id", app.requirePermission("movies:read", app.showMovieByExternalIDHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.requirePermission("movies:read", app.showMovieHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id", app.requirePermission("movies:write", app.replaceMovieHandler))
 	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", app.requirePermission("movies:write", app.updateMovieHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id", app.requirePermission("movies:write", app.deleteMovieHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/merge", app.requirePermission("movies:write", app.mergeMovieHandler))
+
+	// movies trash
+	staticRouter.HandlerFunc(http.MethodGet, "/v1/movies/trash", app.requirePermission("movies:write", app.listTrashedMoviesHandler))
+	staticRouter.HandlerFunc(http.MethodDelete, "/v1/movies/trash", app.requirePermission("movies:admin", app.purgeTrashedMoviesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/restore", app.requirePermission("movies:write", app.restoreMovieHandler))
+
+	// movie revisions
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/revisions", app.requirePermission("movies:read", app.listMovieRevisionsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/revisions/:version", app.requirePermission("movies:read", app.showMovieRevisionHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/revert/:version", app.requirePermission("movies:write", app.revertMovieHandler))
+
+	// movie ratings and reviews
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/rating", app.requirePermission("reviews:write", app.rateMovieHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/rating", app.requirePermission("reviews:write", app.deleteMovieRatingHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/reviews", app.requirePermission("movies:read", app.listMovieReviewsHandler))
+
+	// similar movies and recommendations
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/similar", app.requirePermission("movies:read", app.listSimilarMoviesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/recommendations", app.requirePermission("movies:read", app.listRecommendationsHandler))
+
+	// posters; the images are public on purpose, as the poster_url is used in <img> tags which can't send
+	// the Authorization header. Only the upload requires a permission.
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/poster", app.showMoviePosterHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/poster", app.requirePermission("movies:write", app.uploadMoviePosterHandler))
+
+	// translations
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/translations", app.requirePermission("movies:read", app.listMovieTranslationsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/translations/:lang", app.requirePermission("movies:write", app.putMovieTranslationHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/translations/:lang", app.requirePermission("movies:write", app.deleteMovieTranslationHandler))
+
+	// cast and crew
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/credits", app.requirePermission("movies:read", app.listMovieCreditsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/credits", app.requirePermission("movies:write", app.createMovieCreditHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/credits/:credit_id", app.requirePermission("movies:write", app.deleteMovieCreditHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/people", app.requirePermission("movies:read", app.listPeopleHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/people", app.requirePermission("movies:write", app.createPersonHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id", app.requirePermission("movies:read", app.showPersonHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/people/:id", app.requirePermission("movies:write", app.updatePersonHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/people/:id", app.requirePermission("movies:write", app.deletePersonHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id/filmography", app.requirePermission("movies:read", app.showFilmographyHandler))
+
+	// statistics
+	router.HandlerFunc(http.MethodGet, "/v1/stats/movies", app.requirePermission("movies:read", app.showMovieStatsHandler))
+
+	// movie lists
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/lists", app.requirePermission("movies:read", app.listMovieListsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/lists", app.requirePermission("movies:read", app.createMovieListHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/lists/:id", app.requirePermission("movies:read", app.showMovieListHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me/lists/:id", app.requirePermission("movies:read", app.updateMovieListHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/lists/:id", app.requirePermission("movies:read", app.deleteMovieListHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me/lists/:id/order", app.requirePermission("movies:read", app.reorderMovieListHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me/lists/:id/movies/:movie_id", app.requirePermission("movies:read", app.putMovieListEntryHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/lists/:id/movies/:movie_id", app.requirePermission("movies:read", app.deleteMovieListEntryHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/lists/:slug", app.showPublicMovieListHandler)
 
 	// Return the httprouter instance.
 	standard := alice.New(app.recoverPanic, app.enableCORS, app.rateLimit, app.authenticate)
-	return standard.Then(router)
+	return standard.Then(app.withStaticRoutes(staticRouter, router))
 	// return app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router))))
 
 	// TODO Use Composable middleware chains as described in Chapter 6.5 from Alex Edwards book 'Let´s Go'
 }
+
+// The withStaticRoutes() helper returns a handler which dispatches a request to the static router if it
+// has a matching route, and to the main router otherwise.
+func (app *application) withStaticRoutes(static, router *httprouter.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handle, params, _ := static.Lookup(r.Method, r.URL.Path); handle != nil {
+			handle(w, r, params)
+			return
+		}
+
+		router.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/comfortliner/greenlight/internal/data"
+	"github.com/comfortliner/greenlight/internal/validator"
+)
+
+// similarityCache struct holds the current similarity index together with the state of its refresh.
+type similarityCache struct {
+	mu         sync.Mutex
+	index      *data.SimilarityIndex
+	refreshing bool
+
+	// rebuild serializes the builds of requests which have no index yet or didn't find a movie in it.
+	rebuild sync.Mutex
+}
+
+// Add a listSimilarMoviesHandler for the "GET /v1/movies/:id/similar" endpoint.
+// A movie which has been created or restored after the index has been built triggers a rebuild of the index.
+func (app *application) listSimilarMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	filters, ok := app.readScoreFilters(w, r)
+	if !ok {
+		return
+	}
+
+	index, err := app.similarityIndex()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	movies, metadata, err := index.Similar(id, filters)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		_, err = app.models.Movies.Get(id)
+		if err == nil {
+			index, err = app.rebuildSimilarityIndex(index)
+			if err == nil {
+				movies, metadata, err = index.Similar(id, filters)
+			}
+		}
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Add a listRecommendationsHandler for the "GET /v1/users/me/recommendations" endpoint.
+// The recommendations are based on the movies the user has rated or added to one of their lists.
+func (app *application) listRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	filters, ok := app.readScoreFilters(w, r)
+	if !ok {
+		return
+	}
+
+	index, err := app.similarityIndex()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	movies, metadata := index.Recommend(app.contextGetUser(r).ID, filters)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The readScoreFilters() helper reads the pagination of a list which is ordered by score. If the query
+// string is invalid, an error response is sent and false is returned.
+func (app *application) readScoreFilters(w http.ResponseWriter, r *http.Request) (data.Filters, bool) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         "-score",
+		SortSafelist: []string{"-score"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return filters, false
+	}
+
+	return filters, true
+}
+
+// The similarityIndex() helper returns the current similarity index. The first call builds the index, afterwards
+// an index which is older than the refresh interval is rebuilt in the background, while the old index is
+// still used.
+func (app *application) similarityIndex() (*data.SimilarityIndex, error) {
+	app.similarity.mu.Lock()
+
+	index := app.similarity.index
+
+	if index != nil && time.Since(index.BuiltAt()) > app.config.movies.similarityRefresh && !app.similarity.refreshing {
+		app.similarity.refreshing = true
+
+		app.background(func() {
+			defer func() {
+				app.similarity.mu.Lock()
+				app.similarity.refreshing = false
+				app.similarity.mu.Unlock()
+			}()
+
+			index, err := app.models.Movies.BuildSimilarityIndex()
+			if err != nil {
+				app.logger.Error(err.Error())
+				return
+			}
+
+			app.similarity.mu.Lock()
+			app.similarity.index = index
+			app.similarity.mu.Unlock()
+		})
+	}
+
+	app.similarity.mu.Unlock()
+
+	if index != nil {
+		return index, nil
+	}
+
+	// Concurrent first requests wait for a single build.
+	return app.rebuildSimilarityIndex(nil)
+}
+
+// The rebuildSimilarityIndex() helper builds a new similarity index right away, unless the stale index has
+// already been replaced. Concurrent requests wait for a single rebuild and use its index.
+func (app *application) rebuildSimilarityIndex(stale *data.SimilarityIndex) (*data.SimilarityIndex, error) {
+	app.similarity.rebuild.Lock()
+	defer app.similarity.rebuild.Unlock()
+
+	app.similarity.mu.Lock()
+	index := app.similarity.index
+	app.similarity.mu.Unlock()
+
+	if index != stale {
+		return index, nil
+	}
+
+	index, err := app.models.Movies.BuildSimilarityIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	app.similarity.mu.Lock()
+	if app.similarity.index == stale {
+		app.similarity.index = index
+	}
+	app.similarity.mu.Unlock()
+
+	return index, nil
+}
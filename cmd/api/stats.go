@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comfortliner/greenlight/internal/data"
+)
+
+// statsCache struct holds the computed movie statistics per title filter until they expire.
+type statsCache struct {
+	mu      sync.Mutex
+	entries map[string]statsCacheEntry
+}
+
+type statsCacheEntry struct {
+	stats   *data.MovieStats
+	expires time.Time
+}
+
+// The get() method returns the cached statistics of a title filter, if they haven't expired yet.
+func (c *statsCache) get(key string) (*data.MovieStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	return entry.stats, true
+}
+
+// The set() method caches the statistics of a title filter. Expired entries are removed at the same time,
+// so the cache doesn't grow with every title which has been filtered by once.
+func (c *statsCache) set(key string, stats *data.MovieStats, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]statsCacheEntry)
+	}
+
+	now := time.Now()
+
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = statsCacheEntry{stats: stats, expires: now.Add(ttl)}
+}
+
+// Add a showMovieStatsHandler for the "GET /v1/stats/movies" endpoint.
+// The movies can be filtered by title like in the movie list. The statistics are cached for the configured TTL.
+func (app *application) showMovieStatsHandler(w http.ResponseWriter, r *http.Request) {
+	title := app.readString(r.URL.Query(), "title", "")
+
+	// Titles which only differ in case or spacing match the same movies, so they share a cache entry.
+	key := strings.Join(strings.Fields(strings.ToLower(title)), " ")
+
+	stats, ok := app.stats.get(key)
+	if !ok {
+		var err error
+
+		stats, err = app.models.Movies.GetStats(data.MovieQuery{Title: title})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.stats.set(key, stats, app.config.movies.statsTTL)
+	}
+
+	headers := make(http.Header)
+	headers.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(app.config.movies.statsTTL.Seconds())))
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
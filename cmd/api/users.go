@@ -76,7 +76,7 @@ func (app *application) signupUserHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	// Add the standard permissions for the new user.
-	err = app.models.Permissions.AddForUser(user.ID, "movies:read")
+	err = app.models.Permissions.AddForUser(user.ID, "movies:read", "reviews:write")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -0,0 +1,228 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/comfortliner/greenlight/internal/validator"
+)
+
+var (
+	ErrDuplicateCredit = errors.New("duplicate credit")
+)
+
+// CreditRoles holds all roles a person can be credited with.
+var CreditRoles = []string{"actor", "director", "writer", "composer"}
+
+// **********************
+// * Model Definition
+// **********************
+
+// Credit struct to represent the role of a person on a movie.
+type Credit struct {
+	ID           int64  `json:"id"`                  // Unique integer ID for the credit.
+	MovieID      int64  `json:"movie_id"`            // ID of the movie.
+	MovieTitle   string `json:"movie_title"`         // Title of the movie.
+	MovieYear    int32  `json:"movie_year"`          // Release year of the movie.
+	PersonID     int64  `json:"person_id"`           // ID of the credited person.
+	PersonName   string `json:"person_name"`         // Name of the credited person.
+	Role         string `json:"role"`                // One of actor, director, writer or composer.
+	Character    string `json:"character,omitempty"` // Name of the character (actors only).
+	BillingOrder int32  `json:"billing_order"`       // Order of the credit, lower numbers are billed first.
+}
+
+// CreditModel struct type which wraps a sql.DB connection pool.
+type CreditModel struct {
+	DB *sql.DB
+}
+
+// **********************
+// * Data Validation
+// **********************
+
+func ValidateCredit(v *validator.Validator, credit *Credit) {
+	v.Check(credit.PersonID > 0, "person_id", "must be provided")
+
+	v.Check(validator.PermittedValue(credit.Role, CreditRoles...), "role", "must be one of "+strings.Join(CreditRoles, ", "))
+
+	v.Check(credit.Character == "" || credit.Role == "actor", "character", "must only be provided for actors")
+	v.Check(len(credit.Character) <= 255, "character", "must not be more than 255 bytes long")
+
+	v.Check(credit.BillingOrder >= 0, "billing_order", "must not be negative")
+}
+
+// **********************
+// * Data Manipulation
+// **********************
+
+// Insert adds a credit to a movie and increases the version of the movie, as the credits are part of it.
+func (m CreditModel) Insert(credit *Credit, userID int64) error {
+	query := `
+		INSERT INTO movie_credits (movie_id, person_id, role, character, billing_order)
+		OUTPUT INSERTED.id
+		VALUES (@p1, @p2, @p3, @p4, @p5);
+	`
+
+	args := []any{
+		credit.MovieID,
+		credit.PersonID,
+		credit.Role,
+		credit.Character,
+		credit.BillingOrder,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&credit.ID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "mssql: Violation of UNIQUE KEY constraint"):
+			return ErrDuplicateCredit
+		default:
+			return err
+		}
+	}
+
+	err = touchMovie(ctx, tx, credit.MovieID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a credit of a movie and increases the version of the movie.
+func (m CreditModel) Delete(id, movieID int64, userID int64) error {
+	query := `
+		DELETE FROM movie_credits
+		WHERE id = @p1 AND movie_id = @p2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, id, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	err = touchMovie(ctx, tx, movieID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAllForMovie returns the cast and crew of a movie, ordered by role and billing order.
+func (m CreditModel) GetAllForMovie(movieID int64) ([]*Credit, error) {
+	return m.getAll("movie_credits.movie_id = @p1", "movie_credits.role, movie_credits.billing_order, people.name", movieID)
+}
+
+// GetAllForMovies returns the cast and crew of the given movies, keyed by the movie ID.
+func (m CreditModel) GetAllForMovies(movieIDs []int64) (map[int64][]*Credit, error) {
+	js, err := json.Marshal(movieIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	credits, err := m.getAll("movie_credits.movie_id IN (SELECT CAST(value AS bigint) FROM OPENJSON(@p1))", "movie_credits.movie_id, movie_credits.role, movie_credits.billing_order, people.name", string(js))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64][]*Credit, len(movieIDs))
+	for _, id := range movieIDs {
+		result[id] = []*Credit{}
+	}
+
+	for _, credit := range credits {
+		result[credit.MovieID] = append(result[credit.MovieID], credit)
+	}
+
+	return result, nil
+}
+
+// GetAllForPerson returns the filmography of a person, starting with the latest movie.
+func (m CreditModel) GetAllForPerson(personID int64) ([]*Credit, error) {
+	return m.getAll("movie_credits.person_id = @p1", "movies.year DESC, movies.title, movie_credits.role", personID)
+}
+
+// The getAll() method returns all credits matching the WHERE condition. Movies in the trash are left out.
+func (m CreditModel) getAll(where string, orderBy string, args ...any) ([]*Credit, error) {
+	query := `
+		SELECT movie_credits.id, movies.id, movies.title, movies.year, people.id, people.name,
+			movie_credits.role, movie_credits.character, movie_credits.billing_order
+		FROM movie_credits
+		INNER JOIN movies ON movies.id = movie_credits.movie_id
+		INNER JOIN people ON people.id = movie_credits.person_id
+		WHERE movies.deleted_at IS NULL AND ` + where + `
+		ORDER BY ` + orderBy + `, movie_credits.id;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		var credit Credit
+
+		err := rows.Scan(
+			&credit.ID,
+			&credit.MovieID,
+			&credit.MovieTitle,
+			&credit.MovieYear,
+			&credit.PersonID,
+			&credit.PersonName,
+			&credit.Role,
+			&credit.Character,
+			&credit.BillingOrder,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		credits = append(credits, &credit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
@@ -0,0 +1,214 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// duplicateSimilarity is the share of trigrams (Dice coefficient) two titles must have in common
+	// for movies with the same runtime to be treated as likely duplicates.
+	duplicateSimilarity = 0.8
+
+	// maxDuplicates limits the number of duplicate candidates which are returned.
+	maxDuplicates = 10
+)
+
+// titlePunctuation holds the characters which are ignored when titles are compared.
+const titlePunctuation = `.,:;!?'"-&()[]/`
+
+// The normalizedTitle() function returns a SQL expression which normalizes a title for comparison.
+// The title is lower-cased and punctuation and spaces are removed, so "Se7en" matches "se7en" and
+// "Spider-Man: No Way Home" matches "Spiderman No Way Home".
+func normalizedTitle(expr string) string {
+	chars := strings.ReplaceAll(titlePunctuation, "'", "''")
+
+	return `LOWER(REPLACE(TRANSLATE(` + expr + `, N'` + chars + `', N'` + strings.Repeat(" ", len(titlePunctuation)) + `'), ' ', ''))`
+}
+
+// FindDuplicates returns the IDs of the movies which are likely duplicates of the given movie. A movie is a likely
+// duplicate if it has the same normalized title and year, or a similar title and the same runtime.
+func (m MovieModel) FindDuplicates(movie *Movie) ([]int64, error) {
+	query := `
+		SELECT TOP (@p5) id
+		FROM movies
+		WHERE deleted_at IS NULL AND (
+			(year = @p2 AND ` + normalizedTitle("movies.title") + ` = ` + normalizedTitle("@p1") + `)
+			OR (runtime = @p3 AND CAST(2 * (
+					SELECT COUNT(*) FROM OPENJSON(movies.title_trigrams) AS mt
+					WHERE mt.value IN (SELECT value FROM OPENJSON(@p4))
+				) AS float) / NULLIF((SELECT COUNT(*) FROM OPENJSON(movies.title_trigrams)) + (SELECT COUNT(*) FROM OPENJSON(@p4)), 0) >= @p6)
+		)
+		ORDER BY id ASC;
+	`
+
+	args := []any{
+		movie.Title,
+		movie.Year,
+		movie.Runtime,
+		stringList(trigrams(movie.Title)),
+		maxDuplicates,
+		duplicateSimilarity,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Merge merges a duplicate movie into a target movie. The ratings, list entries, credits, translations and external IDs
+// of the duplicate are moved to the target, unless the target already has a rating of the same user, an entry in the
+// same list, the same credit, a translation to the same language or an ID in the same source. The duplicate is then moved to the trash
+// and remembers the target, so requests for it can be redirected. It isn't restored or purged with the trash.
+// A revision is stored for both movies, the version of the target is increased as its related data has changed.
+func (m MovieModel) Merge(id int64, targetID int64, userID int64) error {
+	if id < 1 || targetID < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	// Lock both movies, so neither of them can be changed or deleted while the related data is moved.
+	var count int
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM movies WITH (UPDLOCK, HOLDLOCK)
+		WHERE id IN (@p1, @p2) AND deleted_at IS NULL;
+	`, id, targetID).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count != 2 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		UPDATE movie_ratings
+		SET movie_id = @p2
+		WHERE movie_id = @p1 AND user_id NOT IN (SELECT user_id FROM movie_ratings WHERE movie_id = @p2);
+
+		DELETE FROM movie_ratings WHERE movie_id = @p1;
+
+		UPDATE movie_list_entries
+		SET movie_id = @p2
+		WHERE movie_id = @p1 AND list_id NOT IN (SELECT list_id FROM movie_list_entries WHERE movie_id = @p2);
+
+		DELETE FROM movie_list_entries WHERE movie_id = @p1;
+
+		UPDATE source
+		SET movie_id = @p2
+		FROM movie_credits AS source
+		WHERE source.movie_id = @p1 AND NOT EXISTS (
+			SELECT 1 FROM movie_credits AS target
+			WHERE target.movie_id = @p2 AND target.person_id = source.person_id
+				AND target.role = source.role AND target.character = source.character
+		);
+
+		DELETE FROM movie_credits WHERE movie_id = @p1;
+
+		UPDATE movie_translations
+		SET movie_id = @p2
+		WHERE movie_id = @p1 AND language NOT IN (SELECT language FROM movie_translations WHERE movie_id = @p2);
+
+		DELETE FROM movie_translations WHERE movie_id = @p1;
+
+		UPDATE movie_external_ids
+		SET movie_id = @p2
+		WHERE movie_id = @p1 AND source NOT IN (SELECT source FROM movie_external_ids WHERE movie_id = @p2);
+
+		DELETE FROM movie_external_ids WHERE movie_id = @p1;
+
+		-- Movies which have been merged into the duplicate before now point to the target.
+		UPDATE movies SET merged_into = @p2 WHERE merged_into = @p1;
+
+		UPDATE movies
+		SET deleted_at = getdate(), merged_into = @p2, version = version + 1, updated_at = getdate()
+		` + movieRevisionOutput("@p3", "updated_at") + `
+		WHERE id = @p1;
+	`
+
+	_, err = tx.ExecContext(ctx, query, id, targetID, userID)
+	if err != nil {
+		return err
+	}
+
+	err = storeRevisionExternalIDs(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = touchMovie(ctx, tx, targetID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetMergeTarget returns the ID of the movie a movie has been merged into.
+func (m MovieModel) GetMergeTarget(id int64) (int64, error) {
+	if id < 1 {
+		return 0, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT merged_into
+		FROM movies
+		WHERE id = @p1 AND merged_into IS NOT NULL;
+	`
+
+	var targetID int64
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&targetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return targetID, nil
+}
@@ -0,0 +1,414 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Limits which keep a filter expression from getting too expensive to parse and to run.
+const (
+	maxExpressionLength = 1000
+	maxExpressionDepth  = 20
+)
+
+// ExpressionField struct describes a field which can be used in a filter expression.
+type ExpressionField struct {
+	Column    string   // SQL expression of the field.
+	Number    bool     // The field holds a number, otherwise a string.
+	JSONArray bool     // The field holds a JSON array, "=" tests if the array contains the value.
+	Operators []string // Operators which are permitted for the field.
+}
+
+// ExpressionError is returned for an invalid filter expression. Offset is the (zero-based) position of the
+// character at which the error has been detected.
+type ExpressionError struct {
+	Offset  int
+	Message string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%s (at offset %d)", e.Message, e.Offset)
+}
+
+// Expression struct holds a parsed filter expression, e.g. `year >= 1980 and (genre = "action" or title ~ "club")`.
+//
+// The grammar of an expression is:
+//
+//	or         = and { "or" and }
+//	and        = not { "and" not }
+//	not        = "not" not | "(" or ")" | comparison
+//	comparison = field operator value
+//	operator   = "=" | "!=" | "<" | "<=" | ">" | ">=" | "~"
+//	value      = number | string
+//
+// Strings are enclosed in double quotes, a quote or backslash inside the string is escaped with a backslash.
+// The "~" operator tests if a string contains the value, ignoring the case.
+type Expression struct {
+	root expressionNode
+}
+
+// expressionNode is implemented by the nodes of the abstract syntax tree of an expression.
+type expressionNode interface {
+	sql(args []any) (string, []any)
+}
+
+// expressionLogical struct is an "and" or "or" of two expressions.
+type expressionLogical struct {
+	operator    string
+	left, right expressionNode
+}
+
+// expressionNot struct negates an expression.
+type expressionNot struct {
+	operand expressionNode
+}
+
+// expressionComparison struct compares a field with a value.
+type expressionComparison struct {
+	field    ExpressionField
+	operator string
+	value    any
+}
+
+// ParseExpression parses a filter expression and checks it against the fields of a resource.
+// Errors are returned as *ExpressionError.
+func ParseExpression(input string, fields map[string]ExpressionField) (*Expression, error) {
+	p := &expressionParser{input: []rune(input), fields: fields}
+
+	if len(p.input) > maxExpressionLength {
+		return nil, &ExpressionError{Offset: maxExpressionLength, Message: fmt.Sprintf("must not be more than %d characters long", maxExpressionLength)}
+	}
+
+	err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token.kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", p.token)
+	}
+
+	return &Expression{root: root}, nil
+}
+
+// The sql() method compiles the expression to a SQL condition. The values are added to args
+// and referenced as @pN parameters, so they are never part of the SQL text.
+func (e *Expression) sql(args []any) (string, []any) {
+	return e.root.sql(args)
+}
+
+func (n expressionLogical) sql(args []any) (string, []any) {
+	left, args := n.left.sql(args)
+	right, args := n.right.sql(args)
+
+	return fmt.Sprintf("(%s %s %s)", left, strings.ToUpper(n.operator), right), args
+}
+
+func (n expressionNot) sql(args []any) (string, []any) {
+	operand, args := n.operand.sql(args)
+
+	return fmt.Sprintf("(NOT %s)", operand), args
+}
+
+func (n expressionComparison) sql(args []any) (string, []any) {
+	args = append(args, n.value)
+	param := fmt.Sprintf("@p%d", len(args))
+
+	switch {
+	case n.field.JSONArray && n.operator == "!=":
+		return fmt.Sprintf("(NOT EXISTS (SELECT 1 FROM OPENJSON(%s) WHERE value = %s))", n.field.Column, param), args
+	case n.field.JSONArray:
+		return fmt.Sprintf("(EXISTS (SELECT 1 FROM OPENJSON(%s) WHERE value = %s))", n.field.Column, param), args
+	case n.operator == "~":
+		return fmt.Sprintf("(CHARINDEX(LOWER(%s), LOWER(%s)) > 0)", param, n.field.Column), args
+	case n.operator == "!=":
+		return fmt.Sprintf("(%s <> %s)", n.field.Column, param), args
+	default:
+		return fmt.Sprintf("(%s %s %s)", n.field.Column, n.operator, param), args
+	}
+}
+
+// **********************
+// * Lexer
+// **********************
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+)
+
+// expressionToken struct is a single token of an expression together with its offset.
+type expressionToken struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+func (t expressionToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// expressionParser struct is a recursive descent parser of an expression.
+type expressionParser struct {
+	input  []rune
+	pos    int
+	token  expressionToken
+	depth  int
+	fields map[string]ExpressionField
+}
+
+func (p *expressionParser) errorf(format string, a ...any) error {
+	return &ExpressionError{Offset: p.token.offset, Message: fmt.Sprintf(format, a...)}
+}
+
+// The next() method reads the next token of the input.
+func (p *expressionParser) next() error {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+
+	start := p.pos
+	p.token = expressionToken{offset: start}
+
+	if p.pos >= len(p.input) {
+		p.token.kind = tokenEOF
+		return nil
+	}
+
+	r := p.input[p.pos]
+
+	switch {
+	case r == '(':
+		p.pos++
+		p.token.kind, p.token.text = tokenLeftParen, "("
+	case r == ')':
+		p.pos++
+		p.token.kind, p.token.text = tokenRightParen, ")"
+	case strings.ContainsRune("=!<>~", r):
+		p.pos++
+		if p.pos < len(p.input) && p.input[p.pos] == '=' && r != '=' && r != '~' {
+			p.pos++
+		}
+
+		p.token.kind, p.token.text = tokenOperator, string(p.input[start:p.pos])
+		if p.token.text == "!" {
+			return p.errorf("unknown operator \"!\"")
+		}
+	case r == '"':
+		var sb strings.Builder
+
+		for p.pos++; ; p.pos++ {
+			if p.pos >= len(p.input) {
+				return p.errorf("unterminated string")
+			}
+
+			r = p.input[p.pos]
+
+			if r == '"' {
+				p.pos++
+				break
+			}
+
+			if r == '\\' {
+				p.pos++
+				if p.pos >= len(p.input) || !strings.ContainsRune(`"\`, p.input[p.pos]) {
+					return &ExpressionError{Offset: p.pos - 1, Message: "invalid escape sequence"}
+				}
+				r = p.input[p.pos]
+			}
+
+			sb.WriteRune(r)
+		}
+
+		p.token.kind, p.token.text = tokenString, sb.String()
+	case r == '-' || r == '.' || unicode.IsDigit(r):
+		p.pos++
+		for p.pos < len(p.input) && (p.input[p.pos] == '.' || unicode.IsDigit(p.input[p.pos])) {
+			p.pos++
+		}
+
+		p.token.kind, p.token.text = tokenNumber, string(p.input[start:p.pos])
+	case r == '_' || unicode.IsLetter(r):
+		for p.pos < len(p.input) && (p.input[p.pos] == '_' || unicode.IsLetter(p.input[p.pos]) || unicode.IsDigit(p.input[p.pos])) {
+			p.pos++
+		}
+
+		p.token.kind, p.token.text = tokenIdent, string(p.input[start:p.pos])
+	default:
+		return p.errorf("unexpected character %q", r)
+	}
+
+	return nil
+}
+
+// The keyword() method returns true if the current token is the given (case-insensitive) keyword.
+func (p *expressionParser) keyword(keyword string) bool {
+	return p.token.kind == tokenIdent && strings.EqualFold(p.token.text, keyword)
+}
+
+// **********************
+// * Parser
+// **********************
+
+func (p *expressionParser) parseOr() (expressionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("or") {
+		err = p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = expressionLogical{operator: "or", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (expressionNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("and") {
+		err = p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = expressionLogical{operator: "and", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *expressionParser) parseNot() (expressionNode, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.depth > maxExpressionDepth {
+		return nil, p.errorf("must not be nested more than %d levels deep", maxExpressionDepth)
+	}
+
+	switch {
+	case p.keyword("not"):
+		err := p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return expressionNot{operand: operand}, nil
+	case p.token.kind == tokenLeftParen:
+		err := p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.token.kind != tokenRightParen {
+			return nil, p.errorf("expected \")\" but found %s", p.token)
+		}
+
+		return node, p.next()
+	default:
+		return p.parseComparison()
+	}
+}
+
+// The parseComparison() method parses a comparison and checks it against the permitted fields.
+func (p *expressionParser) parseComparison() (expressionNode, error) {
+	if p.token.kind != tokenIdent {
+		return nil, p.errorf("expected a field but found %s", p.token)
+	}
+
+	name := strings.ToLower(p.token.text)
+
+	field, ok := p.fields[name]
+	if !ok {
+		return nil, p.errorf("unknown field %q", p.token.text)
+	}
+
+	err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token.kind != tokenOperator {
+		return nil, p.errorf("expected an operator but found %s", p.token)
+	}
+
+	operator := p.token.text
+
+	if !slices.Contains(field.Operators, operator) {
+		return nil, p.errorf("operator %q is not permitted for the field %q", operator, name)
+	}
+
+	err = p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	node := expressionComparison{field: field, operator: operator}
+
+	switch {
+	case field.Number && p.token.kind == tokenNumber:
+		number, err := strconv.ParseFloat(p.token.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", p.token.text)
+		}
+		node.value = number
+	case field.Number:
+		return nil, p.errorf("expected a number for the field %q but found %s", name, p.token)
+	case p.token.kind == tokenString:
+		node.value = p.token.text
+	default:
+		return nil, p.errorf("expected a string for the field %q but found %s", name, p.token)
+	}
+
+	return node, p.next()
+}
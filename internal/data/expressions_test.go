@@ -0,0 +1,261 @@
+package data
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testExpressionFields = map[string]ExpressionField{
+	"title": {Column: "movies.title", Operators: []string{"=", "!=", "~"}},
+	"year":  {Column: "movies.year", Number: true, Operators: []string{"=", "!=", "<", "<=", ">", ">="}},
+	"genre": {Column: "movies.genres", JSONArray: true, Operators: []string{"=", "!="}},
+}
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		args  []any // Arguments which are already bound before the expression.
+		sql   string
+		want  []any
+	}{
+		{
+			name:  "comparison",
+			input: `year >= 1980`,
+			sql:   `(movies.year >= @p1)`,
+			want:  []any{1980.0},
+		},
+		{
+			name:  "and binds tighter than or",
+			input: `year = 1 or year = 2 and year = 3`,
+			sql:   `((movies.year = @p1) OR ((movies.year = @p2) AND (movies.year = @p3)))`,
+			want:  []any{1.0, 2.0, 3.0},
+		},
+		{
+			name:  "parentheses override precedence",
+			input: `(year = 1 or year = 2) and year = 3`,
+			sql:   `(((movies.year = @p1) OR (movies.year = @p2)) AND (movies.year = @p3))`,
+			want:  []any{1.0, 2.0, 3.0},
+		},
+		{
+			name:  "not binds tighter than and",
+			input: `not year = 1 and year = 2`,
+			sql:   `((NOT (movies.year = @p1)) AND (movies.year = @p2))`,
+			want:  []any{1.0, 2.0},
+		},
+		{
+			name:  "or is left associative",
+			input: `year = 1 or year = 2 or year = 3`,
+			sql:   `(((movies.year = @p1) OR (movies.year = @p2)) OR (movies.year = @p3))`,
+			want:  []any{1.0, 2.0, 3.0},
+		},
+		{
+			name:  "keywords and fields ignore the case",
+			input: `NOT Year != 1 AND TITLE ~ "club"`,
+			sql:   `((NOT (movies.year <> @p1)) AND (CHARINDEX(LOWER(@p2), LOWER(movies.title)) > 0))`,
+			want:  []any{1.0, "club"},
+		},
+		{
+			name:  "parameters continue after bound arguments",
+			input: `title = "a" or genre != "b"`,
+			args:  []any{"x", "y"},
+			sql:   `((movies.title = @p3) OR (NOT EXISTS (SELECT 1 FROM OPENJSON(movies.genres) WHERE value = @p4)))`,
+			want:  []any{"x", "y", "a", "b"},
+		},
+		{
+			name:  "json array contains",
+			input: `genre = "drama"`,
+			sql:   `(EXISTS (SELECT 1 FROM OPENJSON(movies.genres) WHERE value = @p1))`,
+			want:  []any{"drama"},
+		},
+		{
+			name:  "escaped quote and backslash",
+			input: `title = "say \"hi\" \\ bye"`,
+			sql:   `(movies.title = @p1)`,
+			want:  []any{`say "hi" \ bye`},
+		},
+		{
+			name:  "values never end up in the sql",
+			input: `title = "'; DROP TABLE movies; --"`,
+			sql:   `(movies.title = @p1)`,
+			want:  []any{"'; DROP TABLE movies; --"},
+		},
+		{
+			name:  "negative and decimal numbers",
+			input: `year > -1.5`,
+			sql:   `(movies.year > @p1)`,
+			want:  []any{-1.5},
+		},
+		{
+			name:  "maximum depth",
+			input: strings.Repeat("(", maxExpressionDepth-1) + "year = 1" + strings.Repeat(")", maxExpressionDepth-1),
+			sql:   `(movies.year = @p1)`,
+			want:  []any{1.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := ParseExpression(tt.input, testExpressionFields)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sql, args := expression.sql(tt.args)
+
+			if sql != tt.sql {
+				t.Errorf("got sql %s; want %s", sql, tt.sql)
+			}
+
+			if !reflect.DeepEqual(args, tt.want) {
+				t.Errorf("got args %#v; want %#v", args, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		offset  int
+		message string
+	}{
+		{
+			name:    "empty",
+			input:   ``,
+			offset:  0,
+			message: "expected a field but found end of expression",
+		},
+		{
+			name:    "unknown field",
+			input:   `year = 1 and budget > 10`,
+			offset:  13,
+			message: `unknown field "budget"`,
+		},
+		{
+			name:    "operator not permitted",
+			input:   `genre ~ "dra"`,
+			offset:  6,
+			message: `operator "~" is not permitted for the field "genre"`,
+		},
+		{
+			name:    "unknown operator",
+			input:   `year ! 1`,
+			offset:  5,
+			message: `unknown operator "!"`,
+		},
+		{
+			name:    "missing operator",
+			input:   `year 1`,
+			offset:  5,
+			message: `expected an operator but found "1"`,
+		},
+		{
+			name:    "missing value",
+			input:   `year =`,
+			offset:  6,
+			message: `expected a number for the field "year" but found end of expression`,
+		},
+		{
+			name:    "string for a number field",
+			input:   `year = "1999"`,
+			offset:  7,
+			message: `expected a number for the field "year" but found string "1999"`,
+		},
+		{
+			name:    "number for a string field",
+			input:   `title = 7`,
+			offset:  8,
+			message: `expected a string for the field "title" but found "7"`,
+		},
+		{
+			name:    "invalid number",
+			input:   `year = 1.2.3`,
+			offset:  7,
+			message: `invalid number "1.2.3"`,
+		},
+		{
+			name:    "unterminated string",
+			input:   `title = "club`,
+			offset:  8,
+			message: "unterminated string",
+		},
+		{
+			name:    "invalid escape sequence",
+			input:   `title = "a\nb"`,
+			offset:  10,
+			message: "invalid escape sequence",
+		},
+		{
+			name:    "escape at the end",
+			input:   `title = "a\`,
+			offset:  10,
+			message: "invalid escape sequence",
+		},
+		{
+			name:    "unexpected character",
+			input:   `year = 1 & year = 2`,
+			offset:  9,
+			message: `unexpected character '&'`,
+		},
+		{
+			name:    "unclosed parenthesis",
+			input:   `(year = 1`,
+			offset:  9,
+			message: `expected ")" but found end of expression`,
+		},
+		{
+			name:    "trailing token",
+			input:   `year = 1)`,
+			offset:  8,
+			message: `unexpected ")"`,
+		},
+		{
+			name:    "missing right operand",
+			input:   `year = 1 or`,
+			offset:  11,
+			message: "expected a field but found end of expression",
+		},
+		{
+			name:    "too deeply nested",
+			input:   strings.Repeat("(", maxExpressionDepth) + "year = 1" + strings.Repeat(")", maxExpressionDepth),
+			offset:  maxExpressionDepth,
+			message: "must not be nested more than 20 levels deep",
+		},
+		{
+			name:    "too deeply negated",
+			input:   strings.Repeat("not ", maxExpressionDepth) + "year = 1",
+			offset:  4 * maxExpressionDepth,
+			message: "must not be nested more than 20 levels deep",
+		},
+		{
+			name:    "too long",
+			input:   `title = "` + strings.Repeat("a", maxExpressionLength) + `"`,
+			offset:  maxExpressionLength,
+			message: "must not be more than 1000 characters long",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseExpression(tt.input, testExpressionFields)
+
+			var expressionErr *ExpressionError
+
+			if !errors.As(err, &expressionErr) {
+				t.Fatalf("got error %v; want *ExpressionError", err)
+			}
+
+			if expressionErr.Offset != tt.offset {
+				t.Errorf("got offset %d; want %d", expressionErr.Offset, tt.offset)
+			}
+
+			if expressionErr.Message != tt.message {
+				t.Errorf("got message %q; want %q", expressionErr.Message, tt.message)
+			}
+		})
+	}
+}
@@ -1,6 +1,12 @@
 package data
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
 	"strings"
 
 	"github.com/comfortliner/greenlight/internal/validator"
@@ -9,29 +15,194 @@ import (
 type Filters struct {
 	Page         int
 	PageSize     int
-	Sort         string
+	Sort         string // Comma separated sort keys, e.g. "-year,title".
 	SortSafelist []string
+	Cursor       *Cursor  // Switches to keyset pagination if it isn't nil.
+	TextColumns  []string // Sort columns which hold strings, the keys of a cursor are checked against them.
 }
 
-// Check that the client-provided Sort field matches one of the entries in our safelist.
-// If it does, extract the column name from the Sort field by stripping the leading hyphen character (if one exists).
-func (f Filters) sortColumn() string {
-	for _, safeValue := range f.SortSafelist {
-		if f.Sort == safeValue {
-			return strings.TrimPrefix(f.Sort, "-")
+// cursorTiebreaker is the tiebreaker column of the lists which support keyset pagination.
+const cursorTiebreaker = "id"
+
+// Cursor struct marks the position of a record in a sorted list for keyset pagination. Instead of
+// skipping a number of records, the next page starts right after the sort keys of the cursor.
+// This keeps deep pages fast and the pages stable while records are inserted.
+// An empty cursor (without a sort) requests the first page.
+type Cursor struct {
+	Sort     string `json:"s,omitempty"` // The sort the cursor has been created for.
+	Keys     []any  `json:"k,omitempty"` // Values of the sort columns (including the tiebreaker) of the record.
+	Backward bool   `json:"b,omitempty"` // Return the records before instead of after the cursor.
+}
+
+// DecodeCursor decodes a cursor from its opaque string representation. The empty string
+// decodes to the cursor of the first page.
+func DecodeCursor(s string) (*Cursor, error) {
+	var cursor Cursor
+
+	if s == "" {
+		return &cursor, nil
+	}
+
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(strings.NewReader(string(js)))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(&cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cursor.Keys) == 0 {
+		return nil, errors.New("invalid cursor keys")
+	}
+
+	// JSON only gives us numbers (float64) and strings, anything else has been tampered with.
+	for _, key := range cursor.Keys {
+		switch key.(type) {
+		case float64, string:
+		default:
+			return nil, errors.New("invalid cursor key")
 		}
 	}
 
-	panic("unsafe sort parameter: " + f.Sort)
+	return &cursor, nil
+}
+
+// String encodes the cursor to an opaque string which can be passed back by the client.
+func (c Cursor) String() string {
+	js, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(js)
 }
 
-// Return the sort direction ("ASC" or "DESC") depending on the prefic character of the Sort field.
-func (f Filters) sortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
-		return "DESC"
+// IsFirstPage returns true if the cursor requests the first page.
+func (c Cursor) IsFirstPage() bool {
+	return c.Sort == ""
+}
+
+// sortColumn struct holds a column of the ORDER BY clause together with its direction ("ASC" or "DESC").
+type sortColumn struct {
+	Name      string
+	Direction string
+}
+
+// Return the keys of the comma separated Sort field.
+func (f Filters) sortKeys() []string {
+	return strings.Split(f.Sort, ",")
+}
+
+// Check that every key of the client-provided Sort field matches one of the entries in our safelist.
+// If it does, extract the column name by stripping the leading hyphen character (if one exists), which
+// also sets the sort direction to descending. The tiebreaker column is added as last column to get a
+// deterministic order, unless the records are already sorted by it.
+func (f Filters) sortColumns(tiebreaker string) []sortColumn {
+	columns := []sortColumn{}
+
+	for _, key := range f.sortKeys() {
+		if !slices.Contains(f.SortSafelist, key) {
+			panic("unsafe sort parameter: " + key)
+		}
+
+		column := sortColumn{Name: strings.TrimPrefix(key, "-"), Direction: "ASC"}
+		if strings.HasPrefix(key, "-") {
+			column.Direction = "DESC"
+		}
+
+		columns = append(columns, column)
+	}
+
+	if !slices.ContainsFunc(columns, func(c sortColumn) bool { return c.Name == tiebreaker }) {
+		columns = append(columns, sortColumn{Name: tiebreaker, Direction: "ASC"})
+	}
+
+	return columns
+}
+
+// The orderBy() function returns the columns of an ORDER BY clause, e.g. "year DESC, title ASC, id ASC".
+func orderBy(columns []sortColumn) string {
+	clauses := make([]string, len(columns))
+
+	for i, column := range columns {
+		clauses[i] = column.Name + " " + column.Direction
+	}
+
+	return strings.Join(clauses, ", ")
+}
+
+// The keysetQuery() function wraps a list query (which must not be ordered) to return the page after (or
+// before) the cursor. The page is ordered by the sort columns and starts right after the sort keys of the
+// cursor, which are appended to the arguments. Columns computed by the list query can be sorted by their
+// alias. One more record than the page size is returned, to tell whether there are more records in the
+// direction of the request.
+func keysetQuery(query string, args []any, columns []sortColumn, cursor Cursor, limit int) (string, []any) {
+	// Going backward the order is reversed, so the records closest to the cursor come first.
+	if cursor.Backward {
+		reversed := make([]sortColumn, len(columns))
+
+		for i, column := range columns {
+			reversed[i] = sortColumn{Name: column.Name, Direction: "DESC"}
+			if column.Direction == "DESC" {
+				reversed[i].Direction = "ASC"
+			}
+		}
+
+		columns = reversed
+	}
+
+	// A record comes after the cursor if its first sort column is after the key of the cursor,
+	// or the first column is equal and the second is after the key, and so on.
+	where := ""
+
+	if !cursor.IsFirstPage() {
+		conditions := []string{}
+
+		for i, column := range columns {
+			condition := []string{}
+
+			for j, previous := range columns[:i] {
+				condition = append(condition, fmt.Sprintf("%s = @p%d", previous.Name, len(args)+1+j))
+			}
+
+			comparison := ">"
+			if column.Direction == "DESC" {
+				comparison = "<"
+			}
+
+			condition = append(condition, fmt.Sprintf("%s %s @p%d", column.Name, comparison, len(args)+1+i))
+			conditions = append(conditions, "("+strings.Join(condition, " AND ")+")")
+		}
+
+		where = "WHERE " + strings.Join(conditions, " OR ")
+
+		args = append(args, cursor.Keys...)
 	}
 
-	return "ASC"
+	query = fmt.Sprintf(`
+		SELECT TOP (@p%d) * FROM (%s) AS page
+		%s
+		ORDER BY %s;
+	`, len(args)+1, query, where, orderBy(columns))
+
+	args = append(args, limit+1)
+
+	return query, args
+}
+
+// Return the number of records per page.
+func (f Filters) limit() int {
+	return f.PageSize
+}
+
+// Return the number of records to skip for the current page.
+func (f Filters) offset() int {
+	return (f.Page - 1) * f.PageSize
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
@@ -41,5 +212,94 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 
-	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+	// Every sort key must be in the safelist, and every column may only be sorted by once.
+	sorted := make(map[string]bool)
+
+	for _, key := range f.sortKeys() {
+		if !validator.PermittedValue(key, f.SortSafelist...) {
+			v.AddError("sort", fmt.Sprintf("invalid sort value %q", key))
+			continue
+		}
+
+		column := strings.TrimPrefix(key, "-")
+		v.Check(!sorted[column], "sort", fmt.Sprintf("duplicate sort value %q", key))
+		sorted[column] = true
+	}
+
+	if f.Cursor != nil && !f.Cursor.IsFirstPage() {
+		v.Check(f.Cursor.Sort == f.Sort, "cursor", "must be used with the sort it has been created for")
+
+		// A tampered cursor could leave parameters of the keyset query unbound, or compare a column
+		// with a key of another type. The keys are only checked against a valid sort.
+		if _, invalid := v.Errors["sort"]; !invalid {
+			columns := f.sortColumns(cursorTiebreaker)
+
+			v.Check(len(f.Cursor.Keys) == len(columns), "cursor", "must be a cursor returned by a previous request")
+
+			for i, column := range columns[:min(len(columns), len(f.Cursor.Keys))] {
+				_, isText := f.Cursor.Keys[i].(string)
+				v.Check(isText == slices.Contains(f.TextColumns, column.Name), "cursor", "must be a cursor returned by a previous request")
+			}
+		}
+	}
+}
+
+// Metadata struct holds the pagination metadata.
+type Metadata struct {
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
+}
+
+// The calculateMetadata() function calculates the appropriate pagination metadata values given the total number
+// of records, current page, and page size values. If there are no records, an empty Metadata struct is returned.
+func calculateMetadata(totalRecords, page, pageSize int) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		TotalRecords: totalRecords,
+	}
+}
+
+// The calculateCursorMetadata() function calculates the keyset pagination metadata given the cursors of the
+// records of the current page (in their natural order) and whether there are more records in the direction
+// of the request. The total number of records isn't counted in keyset mode.
+func calculateCursorMetadata(f Filters, cursors []Cursor, more bool) Metadata {
+	if len(cursors) == 0 {
+		return Metadata{}
+	}
+
+	metadata := Metadata{PageSize: f.PageSize}
+
+	first := cursors[0]
+	first.Backward = true
+	last := cursors[len(cursors)-1]
+
+	// Going forward there are records before the page unless it is the first one. Going backward we
+	// always came from the records after the page.
+	if f.Cursor.Backward {
+		metadata.NextCursor = last.String()
+		if more {
+			metadata.PrevCursor = first.String()
+		}
+	} else {
+		if more {
+			metadata.NextCursor = last.String()
+		}
+		if !f.Cursor.IsFirstPage() {
+			metadata.PrevCursor = first.String()
+		}
+	}
+
+	return metadata
 }
@@ -0,0 +1,241 @@
+package data
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/comfortliner/greenlight/internal/validator"
+)
+
+var testSortSafelist = []string{"id", "title", "year", "-id", "-title", "-year"}
+
+func TestKeysetQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		sort   string
+		cursor Cursor
+		args   []any // Arguments which are already bound by the list query.
+		sql    string
+		want   []any
+	}{
+		{
+			name:   "first page",
+			sort:   "title",
+			cursor: Cursor{},
+			sql:    `SELECT TOP (@p1) * FROM (SELECT * FROM movies) AS page ORDER BY title ASC, id ASC;`,
+			want:   []any{21},
+		},
+		{
+			name:   "forward",
+			sort:   "title",
+			cursor: Cursor{Sort: "title", Keys: []any{"b", 7.0}},
+			sql: `SELECT TOP (@p3) * FROM (SELECT * FROM movies) AS page ` +
+				`WHERE (title > @p1) OR (title = @p1 AND id > @p2) ` +
+				`ORDER BY title ASC, id ASC;`,
+			want: []any{"b", 7.0, 21},
+		},
+		{
+			name:   "descending column",
+			sort:   "-year,title",
+			cursor: Cursor{Sort: "-year,title", Keys: []any{1999.0, "b", 7.0}},
+			sql: `SELECT TOP (@p4) * FROM (SELECT * FROM movies) AS page ` +
+				`WHERE (year < @p1) OR (year = @p1 AND title > @p2) OR (year = @p1 AND title = @p2 AND id > @p3) ` +
+				`ORDER BY year DESC, title ASC, id ASC;`,
+			want: []any{1999.0, "b", 7.0, 21},
+		},
+		{
+			name:   "backward reverses every direction",
+			sort:   "-year,title",
+			cursor: Cursor{Sort: "-year,title", Keys: []any{1999.0, "b", 7.0}, Backward: true},
+			sql: `SELECT TOP (@p4) * FROM (SELECT * FROM movies) AS page ` +
+				`WHERE (year > @p1) OR (year = @p1 AND title < @p2) OR (year = @p1 AND title = @p2 AND id < @p3) ` +
+				`ORDER BY year ASC, title DESC, id DESC;`,
+			want: []any{1999.0, "b", 7.0, 21},
+		},
+		{
+			name:   "sorted by the tiebreaker",
+			sort:   "-id",
+			cursor: Cursor{Sort: "-id", Keys: []any{7.0}},
+			sql:    `SELECT TOP (@p2) * FROM (SELECT * FROM movies) AS page WHERE (id < @p1) ORDER BY id DESC;`,
+			want:   []any{7.0, 21},
+		},
+		{
+			name:   "parameters continue after bound arguments",
+			sort:   "title",
+			cursor: Cursor{Sort: "title", Keys: []any{"b", 7.0}},
+			args:   []any{"x", "y"},
+			sql: `SELECT TOP (@p5) * FROM (SELECT * FROM movies) AS page ` +
+				`WHERE (title > @p3) OR (title = @p3 AND id > @p4) ` +
+				`ORDER BY title ASC, id ASC;`,
+			want: []any{"x", "y", "b", 7.0, 21},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := Filters{Sort: tt.sort, SortSafelist: testSortSafelist}
+
+			query, args := keysetQuery("SELECT * FROM movies", tt.args, filters.sortColumns(cursorTiebreaker), tt.cursor, 20)
+
+			// The whitespace of the query template doesn't matter.
+			sql := strings.Join(strings.Fields(query), " ")
+
+			if sql != tt.sql {
+				t.Errorf("got sql %s; want %s", sql, tt.sql)
+			}
+
+			if !reflect.DeepEqual(args, tt.want) {
+				t.Errorf("got args %#v; want %#v", args, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	cursor := Cursor{Sort: "-year,title", Keys: []any{1999.0, "b", 7.0}, Backward: true}
+
+	decoded, err := DecodeCursor(cursor.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*decoded, cursor) {
+		t.Errorf("got cursor %#v; want %#v", *decoded, cursor)
+	}
+
+	decoded, err = DecodeCursor("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !decoded.IsFirstPage() {
+		t.Errorf("got cursor %#v; want the first page", *decoded)
+	}
+}
+
+func TestDecodeCursorErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "not base64", input: "not a cursor!"},
+		{name: "not json", input: "bm90IGpzb24"},
+		{name: "unknown field", input: encodeTestCursor(`{"s":"title","k":["b",7],"x":1}`)},
+		{name: "no keys", input: encodeTestCursor(`{"s":"title"}`)},
+		{name: "null key", input: encodeTestCursor(`{"s":"title","k":[null,7]}`)},
+		{name: "object key", input: encodeTestCursor(`{"s":"title","k":[{"a":1},7]}`)},
+		{name: "array key", input: encodeTestCursor(`{"s":"title","k":[["b"],7]}`)},
+		{name: "boolean key", input: encodeTestCursor(`{"s":"title","k":[true,7]}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := DecodeCursor(tt.input)
+			if err == nil {
+				t.Errorf("got cursor %#v; want an error", *cursor)
+			}
+		})
+	}
+}
+
+func TestValidateFiltersCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		sort   string
+		cursor *Cursor
+		valid  bool
+	}{
+		{
+			name:   "no cursor",
+			sort:   "title",
+			cursor: nil,
+			valid:  true,
+		},
+		{
+			name:   "first page",
+			sort:   "title",
+			cursor: &Cursor{},
+			valid:  true,
+		},
+		{
+			name:   "matching cursor",
+			sort:   "-year,title",
+			cursor: &Cursor{Sort: "-year,title", Keys: []any{1999.0, "b", 7.0}},
+			valid:  true,
+		},
+		{
+			name:   "sorted by the tiebreaker",
+			sort:   "-id",
+			cursor: &Cursor{Sort: "-id", Keys: []any{7.0}},
+			valid:  true,
+		},
+		{
+			name:   "sort mismatch",
+			sort:   "year",
+			cursor: &Cursor{Sort: "title", Keys: []any{1999.0, 7.0}},
+			valid:  false,
+		},
+		{
+			name:   "invalid sort",
+			sort:   "budget",
+			cursor: &Cursor{Sort: "budget", Keys: []any{1.0, 7.0}},
+			valid:  false,
+		},
+		{
+			name:   "too few keys",
+			sort:   "-year,title",
+			cursor: &Cursor{Sort: "-year,title", Keys: []any{1999.0, "b"}},
+			valid:  false,
+		},
+		{
+			name:   "too many keys",
+			sort:   "title",
+			cursor: &Cursor{Sort: "title", Keys: []any{"b", 7.0, 8.0}},
+			valid:  false,
+		},
+		{
+			name:   "number for a text column",
+			sort:   "title",
+			cursor: &Cursor{Sort: "title", Keys: []any{1.0, 7.0}},
+			valid:  false,
+		},
+		{
+			name:   "string for a number column",
+			sort:   "year",
+			cursor: &Cursor{Sort: "year", Keys: []any{"1999", 7.0}},
+			valid:  false,
+		},
+		{
+			name:   "string for the tiebreaker",
+			sort:   "title",
+			cursor: &Cursor{Sort: "title", Keys: []any{"b", "7"}},
+			valid:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := Filters{
+				Page:         1,
+				PageSize:     20,
+				Sort:         tt.sort,
+				SortSafelist: testSortSafelist,
+				Cursor:       tt.cursor,
+				TextColumns:  []string{"title"},
+			}
+
+			v := validator.New()
+			ValidateFilters(v, filters)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("got valid %t; want %t (errors %v)", v.Valid(), tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func encodeTestCursor(js string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(js))
+}
@@ -0,0 +1,425 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/comfortliner/greenlight/internal/validator"
+)
+
+const (
+	ListKindWatchlist = "watchlist"
+	ListKindCustom    = "custom"
+
+	ListVisibilityPrivate = "private"
+	ListVisibilityPublic  = "public"
+)
+
+// **********************
+// * Model Definition
+// **********************
+
+// MovieList struct to represent a user-owned list of movies. Every user has a built-in watchlist and
+// any number of custom lists.
+type MovieList struct {
+	ID          int64             `json:"id"`                // Unique integer ID for the list.
+	CreatedAt   time.Time         `json:"created_at"`        // Timestamp for when the list has been created.
+	UserID      int64             `json:"user_id"`           // ID of the user who owns the list.
+	Kind        string            `json:"kind"`              // Either "watchlist" or "custom".
+	Name        string            `json:"name"`              // Name of the list.
+	Description string            `json:"description"`       // Optional description of the list.
+	Visibility  string            `json:"visibility"`        // Either "private" or "public".
+	Slug        string            `json:"slug"`              // Unguessable identifier to share a public list.
+	Version     int32             `json:"version"`           // The version number starts at 1 and will be incremented each time the list is updated.
+	Entries     []*MovieListEntry `json:"entries,omitempty"` // The movies of the list in their order.
+}
+
+// MovieListEntry struct to represent a single movie on a list.
+type MovieListEntry struct {
+	MovieID  int64     `json:"movie_id"`       // ID of the movie.
+	Title    string    `json:"title"`          // Title of the movie.
+	Year     int32     `json:"year,omitempty"` // Release year of the movie.
+	Position int32     `json:"position"`       // Position of the movie on the list, starting with 1.
+	Note     string    `json:"note"`           // Personal note of the list owner.
+	AddedAt  time.Time `json:"added_at"`       // Timestamp for when the movie has been added to the list.
+}
+
+// MovieListModel struct type which wraps a sql.DB connection pool.
+type MovieListModel struct {
+	DB *sql.DB
+}
+
+// IsWatchlist returns true if the list is the built-in watchlist of a user.
+func (l *MovieList) IsWatchlist() bool {
+	return l.Kind == ListKindWatchlist
+}
+
+// **********************
+// * Data Validation
+// **********************
+
+func ValidateMovieList(v *validator.Validator, list *MovieList) {
+	v.Check(list.Name != "", "name", "must be provided")
+	v.Check(len(list.Name) <= 255, "name", "must not be more than 255 bytes long")
+
+	v.Check(len(list.Description) <= 1000, "description", "must not be more than 1000 bytes long")
+
+	v.Check(validator.PermittedValue(list.Visibility, ListVisibilityPrivate, ListVisibilityPublic), "visibility", "must be private or public")
+}
+
+func ValidateMovieListNote(v *validator.Validator, note string) {
+	v.Check(len(note) <= 1000, "note", "must not be more than 1000 bytes long")
+}
+
+// **********************
+// * Data Manipulation
+// **********************
+
+// Insert adds a new list. An unguessable slug is generated to share the list once it is public.
+func (m MovieListModel) Insert(list *MovieList) error {
+	slug, err := randomString()
+	if err != nil {
+		return err
+	}
+
+	list.Slug = slug
+
+	query := `
+		INSERT INTO movie_lists (user_id, kind, name, description, visibility, slug)
+		OUTPUT INSERTED.id, INSERTED.created_at, INSERTED.version
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6);
+	`
+
+	args := []any{
+		list.UserID,
+		list.Kind,
+		list.Name,
+		list.Description,
+		list.Visibility,
+		list.Slug,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&list.ID, &list.CreatedAt, &list.Version)
+}
+
+// GetWatchlist returns the built-in watchlist of a user. The watchlist is created on first access.
+func (m MovieListModel) GetWatchlist(userID int64) (*MovieList, error) {
+	list, err := m.get("user_id = @p1 AND kind = 'watchlist'", userID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		return list, err
+	}
+
+	list = &MovieList{
+		UserID:     userID,
+		Kind:       ListKindWatchlist,
+		Name:       "Watchlist",
+		Visibility: ListVisibilityPrivate,
+	}
+
+	// A concurrent request of the same user may have created the watchlist in the meantime,
+	// which violates the unique watchlist index. The watchlist is read again in this case.
+	err = m.Insert(list)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "movie_lists_watchlist_idx"):
+			return m.get("user_id = @p1 AND kind = 'watchlist'", userID)
+		default:
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+// Get returns a list of the given user. Lists of other users are reported as not found.
+func (m MovieListModel) Get(id, userID int64) (*MovieList, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	return m.get("id = @p1 AND user_id = @p2", id, userID)
+}
+
+// GetBySlug returns a public list by its slug. Private lists are reported as not found.
+func (m MovieListModel) GetBySlug(slug string) (*MovieList, error) {
+	return m.get("slug = @p1 AND visibility = 'public'", slug)
+}
+
+// The get() method returns the first list matching the given WHERE condition.
+func (m MovieListModel) get(where string, args ...any) (*MovieList, error) {
+	query := `
+		SELECT id, created_at, user_id, kind, name, description, visibility, slug, version
+		FROM movie_lists
+		WHERE ` + where
+
+	var list MovieList
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&list.ID,
+		&list.CreatedAt,
+		&list.UserID,
+		&list.Kind,
+		&list.Name,
+		&list.Description,
+		&list.Visibility,
+		&list.Slug,
+		&list.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &list, nil
+}
+
+// GetAllForUser returns all lists of a user, starting with the watchlist.
+func (m MovieListModel) GetAllForUser(userID int64) ([]*MovieList, error) {
+	query := `
+		SELECT id, created_at, user_id, kind, name, description, visibility, slug, version
+		FROM movie_lists
+		WHERE user_id = @p1
+		ORDER BY CASE kind WHEN 'watchlist' THEN 0 ELSE 1 END, name ASC, id ASC;
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	lists := []*MovieList{}
+
+	for rows.Next() {
+		var list MovieList
+
+		err := rows.Scan(
+			&list.ID,
+			&list.CreatedAt,
+			&list.UserID,
+			&list.Kind,
+			&list.Name,
+			&list.Description,
+			&list.Visibility,
+			&list.Slug,
+			&list.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		lists = append(lists, &list)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+// Update stores the name, description and visibility of a list.
+func (m MovieListModel) Update(list *MovieList) error {
+	query := `
+		UPDATE movie_lists
+		SET name = @p1, description = @p2, visibility = @p3, version = version + 1
+		OUTPUT INSERTED.version
+		WHERE id = @p4 AND version = @p5;
+	`
+
+	args := []any{
+		list.Name,
+		list.Description,
+		list.Visibility,
+		list.ID,
+		list.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&list.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a custom list of a user. The built-in watchlist can't be deleted.
+func (m MovieListModel) Delete(id, userID int64) error {
+	query := `
+		DELETE FROM movie_lists
+		WHERE id = @p1 AND user_id = @p2 AND kind = 'custom'
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetEntries returns the movies of a list in their order. Movies in the trash are left out.
+func (m MovieListModel) GetEntries(listID int64) ([]*MovieListEntry, error) {
+	query := `
+		SELECT movie_list_entries.movie_id, movies.title, movies.year, movie_list_entries.position,
+			movie_list_entries.note, movie_list_entries.added_at
+		FROM movie_list_entries
+		INNER JOIN movies ON movies.id = movie_list_entries.movie_id
+		WHERE movie_list_entries.list_id = @p1 AND movies.deleted_at IS NULL
+		ORDER BY movie_list_entries.position ASC, movie_list_entries.movie_id ASC;
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := []*MovieListEntry{}
+
+	for rows.Next() {
+		var entry MovieListEntry
+
+		err := rows.Scan(
+			&entry.MovieID,
+			&entry.Title,
+			&entry.Year,
+			&entry.Position,
+			&entry.Note,
+			&entry.AddedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// UpsertEntry adds a movie to the end of a list, or changes the note if the movie is already on the list.
+// HOLDLOCK makes the MERGE atomic, so two concurrent adds of the movie can't both insert, and the entries of
+// the list stay locked, so concurrent adds of different movies can't get the same position.
+func (m MovieListModel) UpsertEntry(listID, movieID int64, note string) error {
+	query := `
+		MERGE movie_list_entries WITH (HOLDLOCK) AS target
+		USING (
+			SELECT @p1 AS list_id, @p2 AS movie_id,
+				(SELECT COALESCE(MAX(position), 0) + 1 FROM movie_list_entries WITH (UPDLOCK, HOLDLOCK) WHERE list_id = @p1) AS position
+		) AS source
+		ON target.list_id = source.list_id AND target.movie_id = source.movie_id
+		WHEN MATCHED THEN
+			UPDATE SET note = @p3
+		WHEN NOT MATCHED THEN
+			INSERT (list_id, movie_id, position, note)
+			VALUES (source.list_id, source.movie_id, source.position, @p3);
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, listID, movieID, note)
+	return err
+}
+
+// DeleteEntry removes a movie from a list.
+func (m MovieListModel) DeleteEntry(listID, movieID int64) error {
+	query := `
+		DELETE FROM movie_list_entries
+		WHERE list_id = @p1 AND movie_id = @p2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, listID, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Reorder sets the positions of the movies of a list to the order of the given movie IDs.
+// Movies of the list which aren't part of movieIDs keep their position, but are moved behind
+// the given movies.
+func (m MovieListModel) Reorder(listID int64, movieIDs []int64) error {
+	js, err := json.Marshal(movieIDs)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE movie_list_entries
+		SET position = COALESCE(ordered.position, @p3 + movie_list_entries.position)
+		FROM movie_list_entries
+		LEFT JOIN (
+			SELECT CAST(value AS bigint) AS movie_id, CAST([key] AS int) + 1 AS position
+			FROM OPENJSON(@p2)
+		) AS ordered ON ordered.movie_id = movie_list_entries.movie_id
+		WHERE movie_list_entries.list_id = @p1;
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, listID, string(js), len(movieIDs))
+	return err
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 )
@@ -11,20 +12,40 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 )
 
+// executor interface is satisfied by *sql.DB and *sql.Tx, so a query can run either on its own
+// or as part of a transaction.
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 // Models struct which wraps all existing models.
 type Models struct {
-	Movies      MovieModel
-	Permissions PermissionModel
-	Tokens      TokenModel
-	Users       UserModel
+	Credits        CreditModel
+	MovieLists     MovieListModel
+	Movies         MovieModel
+	MovieRevisions MovieRevisionModel
+	Translations   MovieTranslationModel
+	People         PersonModel
+	Permissions    PermissionModel
+	Ratings        RatingModel
+	Tokens         TokenModel
+	Users          UserModel
 }
 
 // NewModels method returns a Models struct containing all existing models.
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Movies:      MovieModel{DB: db},
-		Permissions: PermissionModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Users:       UserModel{DB: db},
+		Credits:        CreditModel{DB: db},
+		MovieLists:     MovieListModel{DB: db},
+		Movies:         MovieModel{DB: db},
+		MovieRevisions: MovieRevisionModel{DB: db},
+		Translations:   MovieTranslationModel{DB: db},
+		People:         PersonModel{DB: db},
+		Permissions:    PermissionModel{DB: db},
+		Ratings:        RatingModel{DB: db},
+		Tokens:         TokenModel{DB: db},
+		Users:          UserModel{DB: db},
 	}
 }
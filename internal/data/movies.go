@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/comfortliner/greenlight/internal/validator"
@@ -16,12 +19,137 @@ import (
 
 // Movie struct to represent an individual movie.
 type Movie struct {
-	ID        int64     `json:"id"`                // Unique integer ID for the movie.
-	CreatedAt time.Time `json:"-"`                 // Timestamp for when the movie is added to our database.
-	Title     string    `json:"title"`             // Movie title.
-	Year      int32     `json:"year,omitempty"`    // Movie release year.
-	Runtime   int32     `json:"runtime,omitempty"` // Movie runtime (in minutes).
-	Version   int32     `json:"version"`           // The version number starts at 1 and will be incremented each time the movie information is updated.
+	ID            int64      `json:"id"`                   // Unique integer ID for the movie.
+	CreatedAt     time.Time  `json:"-"`                    // Timestamp for when the movie is added to our database.
+	UpdatedAt     time.Time  `json:"-"`                    // Timestamp for when the movie has been changed last.
+	Title         string     `json:"title"`                // Movie title, translated to the preferred language if possible.
+	Year          int32      `json:"year,omitempty"`       // Movie release year.
+	Runtime       int32      `json:"runtime,omitempty"`    // Movie runtime (in minutes).
+	Genres        []string   `json:"genres,omitempty"`     // Slice of genres for the movie (romance, comedy, etc.).
+	Status        string     `json:"status"`               // Lifecycle status: announced, in_production or released.
+	ReleaseDate   *Date      `json:"release_date"`         // Release date, nil if it isn't known yet.
+	AverageRating float64    `json:"average_rating"`       // Average of all user ratings (1-10), 0 if the movie hasn't been rated yet.
+	RatingCount   int64      `json:"rating_count"`         // Number of user ratings.
+	Version       int32      `json:"version"`              // The version number starts at 1 and will be incremented each time the movie information is updated.
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"` // Timestamp for when the movie has been moved to the trash.
+
+	PosterUpdatedAt *time.Time `json:"-"`                    // Timestamp for when the poster has been uploaded, nil without poster.
+	PosterURL       string     `json:"poster_url,omitempty"` // URL of the poster, changes with every upload.
+
+	// Catalogue metadata.
+	OriginalLanguage    string            `json:"original_language,omitempty"`    // ISO 639-1 code of the original language, e.g. "en".
+	ProductionCountries []string          `json:"production_countries,omitempty"` // ISO 3166-1 alpha-2 codes of the production countries.
+	SpokenLanguages     []string          `json:"spoken_languages,omitempty"`     // ISO 639-1 codes of the spoken languages.
+	Certifications      map[string]string `json:"certifications,omitempty"`       // Age certification by country code, e.g. {"DE": "12"}.
+	Plot                string            `json:"plot,omitempty"`                 // Plot summary in the original language.
+	ExternalIDs         map[string]string `json:"external_ids,omitempty"`         // Identifiers in other databases by source, e.g. {"imdb": "tt0137523"}.
+
+	// Translation to the preferred language of the client, if one is available.
+	OriginalTitle string `json:"original_title,omitempty"` // Original title, only set if the title is translated.
+	Overview      string `json:"overview,omitempty"`       // Translated overview of the movie.
+	Language      string `json:"language,omitempty"`       // Language of the translation.
+
+	// Related resources, which are only embedded on request.
+	Credits []*Credit      `json:"credits,omitempty"` // Cast and crew of the movie.
+	Ratings *RatingSummary `json:"ratings,omitempty"` // Summary of the user ratings.
+}
+
+// Statuses of a movie in its lifecycle. Only released movies need a runtime and a release date in the past.
+const (
+	MovieAnnounced    = "announced"
+	MovieInProduction = "in_production"
+	MovieReleased     = "released"
+)
+
+// MovieStatuses holds all statuses of a movie.
+var MovieStatuses = []string{MovieAnnounced, MovieInProduction, MovieReleased}
+
+// MovieFields holds the JSON fields of a movie which can be selected with a sparse fieldset.
+var MovieFields = []string{"id", "title", "original_title", "overview", "language", "year", "runtime", "genres", "status", "release_date",
+	"original_language", "production_countries", "spoken_languages", "certifications", "plot", "external_ids",
+	"average_rating", "rating_count", "version", "poster_url"}
+
+// ErrDuplicateExternalID is returned if an external ID is already assigned to another movie.
+var ErrDuplicateExternalID = errors.New("duplicate external id")
+
+// ExternalIDSources holds the databases a movie can be linked to, together with the format of their identifiers.
+// An identifier can only be assigned to a single movie.
+var ExternalIDSources = map[string]*regexp.Regexp{
+	"imdb":     regexp.MustCompile(`^tt[0-9]{7,10}$`),
+	"tmdb":     regexp.MustCompile(`^[1-9][0-9]{0,9}$`),
+	"wikidata": regexp.MustCompile(`^Q[1-9][0-9]{0,11}$`),
+}
+
+// MovieIncludes holds the related resources which can be embedded into a movie.
+var MovieIncludes = []string{"genres", "ratings", "credits"}
+
+// movieColumns maps the fields of a movie to their columns. The ratings and the translation are read
+// by an OUTER APPLY (see movieRatingsApply and movieTranslationApply).
+var movieColumns = map[string]string{
+	"id":             "movies.id",
+	"created_at":     "movies.created_at",
+	"updated_at":     "movies.updated_at",
+	"title":          "COALESCE(translation.title, movies.title)",
+	"original_title": "CASE WHEN translation.title IS NULL THEN '' ELSE movies.title END",
+	"overview":       "COALESCE(translation.overview, '')",
+	"language":       "COALESCE(translation.language, '')",
+	"year":           "movies.year",
+	"runtime":        "movies.runtime",
+	"genres":         "movies.genres",
+	"status":         "movies.status",
+	"release_date":   "movies.release_date",
+
+	"original_language":    "movies.original_language",
+	"production_countries": "movies.production_countries",
+	"spoken_languages":     "movies.spoken_languages",
+	"certifications":       "movies.certifications",
+	"plot":                 "movies.plot",
+	"external_ids": `(
+			SELECT '{' + STRING_AGG('"' + STRING_ESCAPE(source, 'json') + '":"' + STRING_ESCAPE(external_id, 'json') + '"', ',') + '}'
+			FROM movie_external_ids
+			WHERE movie_external_ids.movie_id = movies.id
+		)`,
+
+	"average_rating": "COALESCE(ratings.average_rating, 0)",
+	"rating_count":   "ratings.rating_count",
+	"version":        "movies.version",
+	"poster_url":     "movies.poster_updated_at",
+}
+
+// MovieQuery struct holds the filters of a movie list. Pagination and sorting are part of Filters.
+type MovieQuery struct {
+	Title      string      // Words which must be part of the title (typos are tolerated).
+	Genres     []string    // Genres which must all be assigned to the movie.
+	PersonID   int64       // ID of a person who must be credited for the movie in any role.
+	DirectorID int64       // ID of a person who must be credited as director of the movie.
+	Filter     *Expression // Optional filter expression on the MovieFilterFields.
+	Fields     []string    // Sparse fieldset of the MovieFields to return, all fields if nil.
+	Languages  []string    // Preferred languages of the titles, in the order of preference.
+}
+
+// MovieFilterFields holds the fields which can be used in the filter expression of a movie list.
+var MovieFilterFields = map[string]ExpressionField{
+	"title":   {Column: "movies.title", Operators: []string{"=", "!=", "~"}},
+	"year":    {Column: "movies.year", Number: true, Operators: []string{"=", "!=", "<", "<=", ">", ">="}},
+	"runtime": {Column: "movies.runtime", Number: true, Operators: []string{"=", "!=", "<", "<=", ">", ">="}},
+	"genre":   {Column: "movies.genres", JSONArray: true, Operators: []string{"=", "!="}},
+	"status":  {Column: "movies.status", Operators: []string{"=", "!="}},
+	"rating":  {Column: "COALESCE(ratings.average_rating, 0)", Number: true, Operators: []string{"=", "!=", "<", "<=", ">", ">="}},
+
+	"original_language": {Column: "movies.original_language", Operators: []string{"=", "!="}},
+	"country":           {Column: "movies.production_countries", JSONArray: true, Operators: []string{"=", "!="}},
+	"spoken_language":   {Column: "movies.spoken_languages", JSONArray: true, Operators: []string{"=", "!="}},
+
+	// Certifications and external IDs are compared as "country:certification" and "source:id", e.g. "DE:12".
+	"certification": {Column: `(
+			SELECT '[' + STRING_AGG('"' + STRING_ESCAPE([key] + ':' + value, 'json') + '"', ',') + ']'
+			FROM OPENJSON(movies.certifications)
+		)`, JSONArray: true, Operators: []string{"=", "!="}},
+	"external_id": {Column: `(
+			SELECT '[' + STRING_AGG('"' + STRING_ESCAPE(source + ':' + external_id, 'json') + '"', ',') + ']'
+			FROM movie_external_ids
+			WHERE movie_external_ids.movie_id = movies.id
+		)`, JSONArray: true, Operators: []string{"=", "!="}},
 }
 
 // MovieModel struct type which wraps a sql.DB connection pool.
@@ -29,6 +157,12 @@ type MovieModel struct {
 	DB *sql.DB
 }
 
+// MovieTx struct type which wraps a transaction in which several movies are changed at once,
+// e.g. by a bulk import or a batch.
+type MovieTx struct {
+	tx *sql.Tx
+}
+
 // **********************
 // * Data Validation
 // **********************
@@ -37,37 +171,121 @@ func ValidateMovie(v *validator.Validator, movie *Movie) {
 	v.Check(movie.Title != "", "title", "must be provided")
 	v.Check(len(movie.Title) <= 500, "title", "must not be more then 500 bytes long")
 
+	v.Check(movie.Status != "", "status", "must be provided")
+	v.Check(validator.PermittedValue(movie.Status, MovieStatuses...), "status", "must be announced, in_production or released")
+
 	v.Check(movie.Year != 0, "year", "must be provided")
 	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
-	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
 
-	v.Check(movie.Runtime != 0, "runtime", "must be provided")
-	v.Check(movie.Runtime > 0, "runtime", "must be an positive integer")
+	if movie.ReleaseDate != nil {
+		v.Check(int32(movie.ReleaseDate.Year()) == movie.Year, "release_date", "must be in the release year")
+	}
+
+	// Movies which haven't been released yet may have a year in the future and no runtime.
+	if movie.Status == MovieReleased {
+		v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
+
+		if movie.ReleaseDate != nil {
+			v.Check(!movie.ReleaseDate.After(time.Now()), "release_date", "must not be in the future")
+		}
+
+		v.Check(movie.Runtime != 0, "runtime", "must be provided")
+		v.Check(movie.Runtime > 0, "runtime", "must be an positive integer")
+	} else {
+		v.Check(movie.Runtime >= 0, "runtime", "must not be negative")
+	}
+
+	v.Check(movie.Genres != nil, "genres", "must be provided")
+	v.Check(len(movie.Genres) >= 1, "genres", "must contain at least 1 genre")
+	v.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
+	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
+
+	v.Check(movie.OriginalLanguage == "" || validator.LanguageCode(movie.OriginalLanguage), "original_language", "must be an ISO 639-1 language code, e.g. \"en\"")
+
+	v.Check(len(movie.ProductionCountries) <= 20, "production_countries", "must not contain more than 20 countries")
+	v.Check(validator.All(movie.ProductionCountries, validator.CountryCode), "production_countries", "must contain ISO 3166-1 alpha-2 country codes, e.g. \"US\"")
+	v.Check(validator.Unique(movie.ProductionCountries), "production_countries", "must not contain duplicate values")
+
+	v.Check(len(movie.SpokenLanguages) <= 20, "spoken_languages", "must not contain more than 20 languages")
+	v.Check(validator.All(movie.SpokenLanguages, validator.LanguageCode), "spoken_languages", "must contain ISO 639-1 language codes, e.g. \"en\"")
+	v.Check(validator.Unique(movie.SpokenLanguages), "spoken_languages", "must not contain duplicate values")
+
+	for country, certification := range movie.Certifications {
+		key := "certifications." + country
+
+		v.Check(validator.CountryCode(country), key, "must be keyed by an ISO 3166-1 alpha-2 country code")
+		v.Check(certification != "", key, "must be provided")
+		v.Check(len(certification) <= 10, key, "must not be more than 10 bytes long")
+	}
+
+	v.Check(len(movie.Plot) <= 5000, "plot", "must not be more than 5000 bytes long")
+
+	for source, id := range movie.ExternalIDs {
+		key := "external_ids." + source
+
+		rx, ok := ExternalIDSources[source]
+		v.Check(ok, key, "must be a known source (imdb, tmdb or wikidata)")
+		v.Check(!ok || validator.Matches(id, rx), key, "must be a valid identifier of the source")
+	}
 }
 
 // **********************
 // * Data Manipulation
 // **********************
 
-func (m MovieModel) Insert(movie *Movie) error {
+// Insert adds a new movie. The first version of the movie is stored as revision, together with
+// the ID of the acting user.
+func (m MovieModel) Insert(movie *Movie, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// The movie and its external IDs are stored in a transaction, so a duplicate external ID
+	// doesn't leave a movie without its IDs behind.
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = insertMovie(ctx, tx, movie, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// The insertMovie() function inserts a movie with either a *sql.DB or a *sql.Tx.
+func insertMovie(ctx context.Context, q executor, movie *Movie, userID int64) error {
 	query := `
-		INSERT INTO movies (title, year, runtime)
-		OUTPUT INSERTED.id, INSERTED.created_at, INSERTED.version
-		VALUES (@p1, @p2, @p3);
+		INSERT INTO movies (title, year, runtime, genres, title_trigrams, status, release_date,
+			original_language, production_countries, spoken_languages, certifications, plot)
+		` + movieRevisionOutput("@p6", "created_at") + `
+		OUTPUT INSERTED.id, INSERTED.created_at, INSERTED.updated_at, INSERTED.version
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p7, @p8, @p9, @p10, @p11, @p12, @p13);
 	`
 
 	args := []any{
 		movie.Title,
 		movie.Year,
 		movie.Runtime,
+		stringList(movie.Genres),
+		stringList(trigrams(movie.Title)),
+		userID,
+		movie.Status,
+		movie.ReleaseDate,
+		movie.OriginalLanguage,
+		stringList(movie.ProductionCountries),
+		stringList(movie.SpokenLanguages),
+		stringMap(movie.Certifications),
+		movie.Plot,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+	err := q.QueryRowContext(ctx, query, args...).Scan(
 		&movie.ID,
 		&movie.CreatedAt,
+		&movie.UpdatedAt,
 		&movie.Version,
 	)
 
@@ -75,34 +293,99 @@ func (m MovieModel) Insert(movie *Movie) error {
 		return err
 	}
 
-	return nil
+	return setExternalIDs(ctx, q, movie)
+}
+
+// The setExternalIDs() function replaces the external IDs of a movie. If one of the IDs is already
+// assigned to another movie, ErrDuplicateExternalID is returned.
+func setExternalIDs(ctx context.Context, q executor, movie *Movie) error {
+	query := `
+		DELETE FROM movie_external_ids WHERE movie_id = @p1;
+
+		INSERT INTO movie_external_ids (movie_id, source, external_id)
+		SELECT @p1, [key], value FROM OPENJSON(@p2);
+	`
+
+	_, err := q.ExecContext(ctx, query, movie.ID, stringMap(movie.ExternalIDs))
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "mssql: Violation of UNIQUE KEY constraint"):
+			return ErrDuplicateExternalID
+		default:
+			return err
+		}
+	}
+
+	return storeRevisionExternalIDs(ctx, q, movie.ID)
 }
 
 func (m MovieModel) Get(id int64) (*Movie, error) {
+	return m.GetFields(id, nil, nil)
+}
+
+// GetFields returns a movie with only the given fields (all fields if fields is nil) read from the database.
+// The title is translated to the first of the preferred languages the movie has been translated to.
+func (m MovieModel) GetFields(id int64, fields []string, preferred []string) (*Movie, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getMovie(ctx, m.DB, id, fields, preferred)
+}
+
+// The getMovie() function reads a movie with either a *sql.DB or a *sql.Tx.
+func getMovie(ctx context.Context, q executor, id int64, fields []string, preferred []string) (*Movie, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
+	langs, err := languages(preferred)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, dest := movieSelect(fields)
+
 	query := `
-		SELECT id, created_at, title, year, runtime,  version
+		SELECT ` + columns + `
 		FROM movies
-		WHERE id = @p1;
+		` + movieRatingsApply + `
+		` + movieTranslationApply("@p2") + `
+		WHERE movies.id = @p1 AND movies.deleted_at IS NULL;
 	`
 
 	var movie Movie
 
+	err = q.QueryRowContext(ctx, query, id, langs).Scan(dest(&movie)...)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	movie.setPosterURL()
+
+	return &movie, nil
+}
+
+// GetByExternalID returns the movie with the given ID in an external database, e.g. an IMDb ID.
+func (m MovieModel) GetByExternalID(source, externalID string, preferred []string) (*Movie, error) {
+	query := `
+		SELECT movie_external_ids.movie_id
+		FROM movie_external_ids
+		INNER JOIN movies ON movies.id = movie_external_ids.movie_id
+		WHERE movie_external_ids.source = @p1 AND movie_external_ids.external_id = @p2 AND movies.deleted_at IS NULL;
+	`
+
+	var id int64
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&movie.ID,
-		&movie.CreatedAt,
-		&movie.Title,
-		&movie.Year,
-		&movie.Runtime,
-		&movie.Version,
-	)
-
+	err := m.DB.QueryRowContext(ctx, query, source, externalID).Scan(&id)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -112,91 +395,627 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 		}
 	}
 
-	return &movie, nil
+	return getMovie(ctx, m.DB, id, nil, preferred)
+}
+
+// movieRatingsApply computes the average rating and the number of ratings of a movie.
+const movieRatingsApply = `OUTER APPLY (
+			SELECT AVG(CAST(rating AS float)) AS average_rating, COUNT(*) AS rating_count
+			FROM movie_ratings
+			WHERE movie_ratings.movie_id = movies.id
+		) AS ratings`
+
+// The movieTranslationApply() function returns the OUTER APPLY which picks the translation of a movie
+// to the first of the languages (a JSON array in the given parameter) the movie has been translated to.
+func movieTranslationApply(param string) string {
+	return `OUTER APPLY (
+			SELECT TOP 1 movie_translations.language, movie_translations.title, movie_translations.overview
+			FROM movie_translations
+			INNER JOIN OPENJSON(` + param + `) AS languages ON languages.value = movie_translations.language
+			WHERE movie_translations.movie_id = movies.id
+			ORDER BY CAST(languages.[key] AS int)
+		) AS translation`
+}
+
+// The movieRevisionOutput() function returns the OUTPUT INTO clause which stores the changed movie as a revision.
+// The ID of the user (a parameter) and the creation time of the revision (a column of INSERTED) are given.
+// The external IDs are stored in another table, they are added to the revision by storeRevisionExternalIDs().
+func movieRevisionOutput(userParam string, createdAt string) string {
+	return `OUTPUT INSERTED.id, INSERTED.version, INSERTED.title, INSERTED.year, INSERTED.runtime, INSERTED.genres,
+			INSERTED.status, INSERTED.release_date, INSERTED.original_language, INSERTED.production_countries,
+			INSERTED.spoken_languages, INSERTED.certifications, INSERTED.plot, ` + userParam + `, INSERTED.` + createdAt + `
+		INTO movie_revisions (movie_id, version, title, year, runtime, genres, status, release_date, original_language,
+			production_countries, spoken_languages, certifications, plot, user_id, created_at)`
+}
+
+// The storeRevisionExternalIDs() function copies the external IDs of a movie to the revision of its current
+// version, with either a *sql.DB or a *sql.Tx. It must be called whenever a revision has been stored.
+func storeRevisionExternalIDs(ctx context.Context, q executor, movieID int64) error {
+	query := `
+		UPDATE movie_revisions
+		SET external_ids = ` + movieColumns["external_ids"] + `
+		FROM movie_revisions
+		INNER JOIN movies ON movies.id = movie_revisions.movie_id AND movies.version = movie_revisions.version
+		WHERE movies.id = @p1;
+	`
+
+	_, err := q.ExecContext(ctx, query, movieID)
+	return err
+}
+
+// The movieSelect() function returns the select list of the given movie fields (all fields if fields is nil),
+// together with a function returning the scan destinations of a movie in the same order. The id, created_at
+// updated_at and version columns are always selected, as they are needed for cursors and cache validators.
+func movieSelect(fields []string) (string, func(movie *Movie) []any) {
+	selected := []string{"id", "created_at", "updated_at", "version"}
+
+	for _, field := range MovieFields {
+		if (fields == nil || slices.Contains(fields, field)) && !slices.Contains(selected, field) {
+			selected = append(selected, field)
+		}
+	}
+
+	columns := make([]string, len(selected))
+	for i, field := range selected {
+		columns[i] = movieColumns[field] + " AS " + field
+	}
+
+	dest := func(movie *Movie) []any {
+		dest := make([]any, len(selected))
+		for i, field := range selected {
+			dest[i] = movie.scanDest(field)
+		}
+		return dest
+	}
+
+	return strings.Join(columns, ", "), dest
+}
+
+// The scanDest() method returns the scan destination of a movie field.
+func (movie *Movie) scanDest(field string) any {
+	switch field {
+	case "id":
+		return &movie.ID
+	case "created_at":
+		return &movie.CreatedAt
+	case "updated_at":
+		return &movie.UpdatedAt
+	case "title":
+		return &movie.Title
+	case "original_title":
+		return &movie.OriginalTitle
+	case "overview":
+		return &movie.Overview
+	case "language":
+		return &movie.Language
+	case "year":
+		return &movie.Year
+	case "runtime":
+		return &movie.Runtime
+	case "genres":
+		return (*stringList)(&movie.Genres)
+	case "status":
+		return &movie.Status
+	case "release_date":
+		return &movie.ReleaseDate
+	case "original_language":
+		return &movie.OriginalLanguage
+	case "production_countries":
+		return (*stringList)(&movie.ProductionCountries)
+	case "spoken_languages":
+		return (*stringList)(&movie.SpokenLanguages)
+	case "certifications":
+		return (*stringMap)(&movie.Certifications)
+	case "plot":
+		return &movie.Plot
+	case "external_ids":
+		return (*stringMap)(&movie.ExternalIDs)
+	case "average_rating":
+		return &movie.AverageRating
+	case "rating_count":
+		return &movie.RatingCount
+	case "version":
+		return &movie.Version
+	case "poster_url":
+		return &movie.PosterUpdatedAt
+	default:
+		panic("unknown movie field: " + field)
+	}
+}
+
+// The setPosterURL() method sets the URL of the poster after the movie has been read. The upload time
+// is part of the URL, so a new poster is never served from a cache.
+func (movie *Movie) setPosterURL() {
+	if movie.PosterUpdatedAt != nil {
+		movie.PosterURL = fmt.Sprintf("/v1/movies/%d/poster?v=%d", movie.ID, movie.PosterUpdatedAt.UnixMilli())
+	}
 }
 
-func (m MovieModel) GetAll(title string, filters Filters) ([]*Movie, error) {
+// Project returns the given fields of the movie keyed by their JSON names, together with the embedded
+// related resources. It is used to marshal sparse fieldsets.
+func (movie *Movie) Project(fields []string) map[string]any {
+	js := make(map[string]any, len(fields)+2)
+
+	for _, field := range fields {
+		switch field {
+		case "id":
+			js[field] = movie.ID
+		case "title":
+			js[field] = movie.Title
+		case "original_title":
+			js[field] = movie.OriginalTitle
+		case "overview":
+			js[field] = movie.Overview
+		case "language":
+			js[field] = movie.Language
+		case "year":
+			js[field] = movie.Year
+		case "runtime":
+			js[field] = movie.Runtime
+		case "genres":
+			js[field] = movie.Genres
+		case "status":
+			js[field] = movie.Status
+		case "release_date":
+			js[field] = movie.ReleaseDate
+		case "original_language":
+			js[field] = movie.OriginalLanguage
+		case "production_countries":
+			js[field] = movie.ProductionCountries
+		case "spoken_languages":
+			js[field] = movie.SpokenLanguages
+		case "certifications":
+			js[field] = movie.Certifications
+		case "plot":
+			js[field] = movie.Plot
+		case "external_ids":
+			js[field] = movie.ExternalIDs
+		case "average_rating":
+			js[field] = movie.AverageRating
+		case "rating_count":
+			js[field] = movie.RatingCount
+		case "version":
+			js[field] = movie.Version
+		case "poster_url":
+			js[field] = movie.PosterURL
+		}
+	}
+
+	if movie.Credits != nil {
+		js["credits"] = movie.Credits
+	}
+
+	if movie.Ratings != nil {
+		js["ratings"] = movie.Ratings
+	}
+
+	return js
+}
+
+// The listQuery() method returns the query and its arguments to list all movies matching the filters.
+// If paginate is true, only the records of the requested page are returned. Only the fields of the query are
+// selected, together with the fields which are needed for sorting. The returned function gives the scan
+// destinations of a movie.
+func (m MovieModel) listQuery(mq MovieQuery, filters Filters, paginate bool) (string, []any, func(*Movie) []any, error) {
+	terms, err := searchTerms(mq.Title)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	columns := filters.sortColumns(cursorTiebreaker)
+	fields := slices.Clone(mq.Fields)
+
+	for i := range columns {
+		switch columns[i].Name {
+		case "relevance":
+			// Relevance is always ranked from the best to the worst match.
+			columns[i].Direction = "DESC"
+		case "rating":
+			columns[i].Name = "average_rating"
+		}
+
+		if fields != nil && columns[i].Name != "relevance" {
+			fields = append(fields, columns[i].Name)
+		}
+	}
+
+	selectList, dest := movieSelect(fields)
+
+	langs, err := languages(mq.Languages)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	keyset := paginate && filters.Cursor != nil
+
+	// The total is only counted for OFFSET pagination. In keyset mode this would require reading all matching
+	// movies for every page, and without pagination (e.g. an export) it would hold back the first row until
+	// all rows have been read.
+	totalRecords := "COUNT(*) OVER()"
+	if !paginate || keyset {
+		totalRecords = "0"
+	}
+
+	args := []any{
+		terms,
+		minSimilarity,
+		strings.Join(mq.Genres, ","),
+		mq.PersonID,
+		mq.DirectorID,
+		langs,
+	}
+
+	condition := "1 = 1"
+	if mq.Filter != nil {
+		condition, args = mq.Filter.sql(args)
+	}
+
+	// Use COUNT(*) OVER() to get the total number of filtered records in the same query (if counted).
+	//
+	// Every word of the title search (@p1) must either be a part of the original title or one of
+	// its translations, or must share at least @p2 of its trigrams with one of them. The relevance
+	// is the average of the best scores of all words.
+	//
+	// A movie matches the genres filter if its JSON genres array contains all of the
+	// (comma separated) genres in @p3.
+	//
+	// The person (@p4) and director (@p5) filters are ignored if they are 0.
+	//
+	// The title is translated to the first of the preferred languages (@p6) the movie has been translated to.
+	//
+	// The filter expression is compiled to a condition with parameters starting at @p7.
 	query := fmt.Sprintf(`
-		SELECT id, created_at, title, year, runtime, version
+		SELECT %s AS total_records, COALESCE(search.relevance, 0) AS relevance, %s
 		FROM movies
-		WHERE (LOWER(title) = LOWER(@p1) OR @p1 = '')
-		ORDER BY %s %s;
-	`, filters.sortColumn(), filters.sortDirection())
+		%s
+		%s
+		CROSS APPLY (
+			SELECT MIN(t.score) AS min_score, AVG(t.score) AS relevance
+			FROM OPENJSON(@p1) WITH (term nvarchar(255) '$.term', trigrams nvarchar(max) '$.trigrams' AS JSON) AS q
+			CROSS APPLY (
+				SELECT MAX(CASE
+					WHEN CHARINDEX(q.term, LOWER(titles.title)) > 0 THEN 1.0
+					ELSE CAST((
+						SELECT COUNT(*) FROM OPENJSON(q.trigrams) AS qt
+						WHERE qt.value IN (SELECT value FROM OPENJSON(titles.title_trigrams))
+					) AS float) / NULLIF((SELECT COUNT(*) FROM OPENJSON(q.trigrams)), 0)
+				END) AS score
+				FROM (
+					SELECT movies.title, movies.title_trigrams
+					UNION ALL
+					SELECT movie_translations.title, movie_translations.title_trigrams
+					FROM movie_translations
+					WHERE movie_translations.movie_id = movies.id
+				) AS titles
+			) AS t
+		) AS search
+		WHERE movies.deleted_at IS NULL
+		AND (@p1 = '[]' OR search.min_score >= @p2)
+		AND (@p3 = '' OR (
+			SELECT COUNT(DISTINCT g.value) FROM OPENJSON(movies.genres) AS g
+			WHERE g.value IN (SELECT value FROM STRING_SPLIT(@p3, ','))
+		) = (SELECT COUNT(DISTINCT value) FROM STRING_SPLIT(@p3, ',')))
+		AND (@p4 = 0 OR EXISTS (
+			SELECT 1 FROM movie_credits
+			WHERE movie_credits.movie_id = movies.id AND movie_credits.person_id = @p4
+		))
+		AND (@p5 = 0 OR EXISTS (
+			SELECT 1 FROM movie_credits
+			WHERE movie_credits.movie_id = movies.id AND movie_credits.person_id = @p5 AND movie_credits.role = 'director'
+		))
+		AND %s
+	`, totalRecords, selectList, movieRatingsApply, movieTranslationApply("@p6"), condition)
+
+	switch {
+	case keyset:
+		query, args = keysetQuery(query, args, columns, *filters.Cursor, filters.limit())
+	case paginate:
+		// Use OFFSET ... FETCH NEXT to return only the records of the requested page.
+		query += fmt.Sprintf("ORDER BY %s OFFSET @p%d ROWS FETCH NEXT @p%d ROWS ONLY;", orderBy(columns), len(args)+1, len(args)+2)
+		args = append(args, filters.offset(), filters.limit())
+	default:
+		query += fmt.Sprintf("ORDER BY %s;", orderBy(columns))
+	}
+
+	return query, args, dest, nil
+}
+
+// The movieSortKey() function returns the value of a sort column of a movie for its cursor.
+func movieSortKey(column string, movie *Movie, relevance float64) any {
+	switch column {
+	case "title":
+		return movie.Title
+	case "year":
+		return movie.Year
+	case "runtime":
+		return movie.Runtime
+	case "rating":
+		return movie.AverageRating
+	case "relevance":
+		return relevance
+	default:
+		return movie.ID
+	}
+}
+
+// The scanListRow() function scans a row of the listQuery() result.
+func scanListRow(rows *sql.Rows, dest func(*Movie) []any, totalRecords *int, relevance *float64, movie *Movie) error {
+	err := rows.Scan(append([]any{totalRecords, relevance}, dest(movie)...)...)
+	if err != nil {
+		return err
+	}
+
+	movie.setPosterURL()
+
+	return nil
+}
+
+func (m MovieModel) GetAll(mq MovieQuery, filters Filters) ([]*Movie, Metadata, error) {
+	query, args, dest, err := m.listQuery(mq, filters, true)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, title)
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, Metadata{}, err
 	}
 
 	defer rows.Close()
 
+	totalRecords := 0
 	movies := []*Movie{}
+	cursors := []Cursor{}
 
 	for rows.Next() {
 		var movie Movie
+		var relevance float64
 
-		err := rows.Scan(
-			&movie.ID,
-			&movie.CreatedAt,
-			&movie.Title,
-			&movie.Year,
-			&movie.Runtime,
-			&movie.Version,
-		)
+		err := scanListRow(rows, dest, &totalRecords, &relevance, &movie)
 		if err != nil {
-			return nil, err
+			return nil, Metadata{}, err
 		}
 
 		movies = append(movies, &movie)
+		cursor := Cursor{Sort: filters.Sort}
+		for _, column := range filters.sortColumns(cursorTiebreaker) {
+			cursor.Keys = append(cursor.Keys, movieSortKey(column.Name, &movie, relevance))
+		}
+
+		cursors = append(cursors, cursor)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, Metadata{}, err
+	}
+
+	if filters.Cursor == nil {
+		metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+		return movies, metadata, nil
+	}
+
+	// The keyset query returns an additional movie if there are more in the direction of the request.
+	more := len(movies) > filters.limit()
+	if more {
+		movies = movies[:filters.limit()]
+		cursors = cursors[:filters.limit()]
+	}
+
+	// Going backward the movies have been returned in reverse order.
+	if filters.Cursor.Backward {
+		slices.Reverse(movies)
+		slices.Reverse(cursors)
 	}
 
-	return movies, nil
+	metadata := calculateCursorMetadata(filters, cursors, more)
+
+	return movies, metadata, nil
 }
 
-func (m MovieModel) Update(movie *Movie) error {
+// Stream calls fn for every movie matching the filters, without pagination. The movies are passed one
+// by one as they are read from the database, so the whole list never needs to be held in memory.
+// If fn returns an error, the iteration stops and the error is returned.
+func (m MovieModel) Stream(mq MovieQuery, filters Filters, fn func(movie *Movie) error) error {
+	query, args, dest, err := m.listQuery(mq, filters, false)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+
+	for rows.Next() {
+		var movie Movie
+		var relevance float64
+
+		err := scanListRow(rows, dest, &totalRecords, &relevance, &movie)
+		if err != nil {
+			return err
+		}
+
+		err = fn(&movie)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Update stores the changes of a movie and increments its version. The new version of the movie is
+// stored as revision, together with the ID of the acting user.
+func (m MovieModel) Update(movie *Movie, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = updateMovie(ctx, tx, movie, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// The updateMovie() function updates a movie with either a *sql.DB or a *sql.Tx.
+func updateMovie(ctx context.Context, q executor, movie *Movie, userID int64) error {
 	query := `
 		UPDATE movies 
-		SET title = @p1, year = @p2, runtime = @p3, version = version + 1
-		OUTPUT INSERTED.version
-		WHERE id = @p4;
+		SET title = @p1, year = @p2, runtime = @p3, genres = @p4, title_trigrams = @p5, status = @p9, release_date = @p10,
+			original_language = @p11, production_countries = @p12, spoken_languages = @p13, certifications = @p14, plot = @p15,
+			version = version + 1, updated_at = getdate()
+		` + movieRevisionOutput("@p8", "updated_at") + `
+		OUTPUT INSERTED.version, INSERTED.updated_at
+		WHERE id = @p6 AND version = @p7 AND deleted_at IS NULL;
 	`
 
 	args := []any{
 		movie.Title,
 		movie.Year,
 		movie.Runtime,
+		stringList(movie.Genres),
+		stringList(trigrams(movie.Title)),
 		movie.ID,
+		movie.Version,
+		userID,
+		movie.Status,
+		movie.ReleaseDate,
+		movie.OriginalLanguage,
+		stringList(movie.ProductionCountries),
+		stringList(movie.SpokenLanguages),
+		stringMap(movie.Certifications),
+		movie.Plot,
+	}
+
+	// If no matching row could be found, the movie has been updated (or deleted)
+	// since we read it, so return an ErrEditConflict error.
+	err := q.QueryRowContext(ctx, query, args...).Scan(&movie.Version, &movie.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
 	}
 
+	return setExternalIDs(ctx, q, movie)
+}
+
+// The touchMovie() function increases the version of a movie whose related data (e.g. a credit or a translation)
+// has been changed, so clients notice the change by the version and the ETag. A revision is stored for the new
+// version like for every other change of the movie.
+func touchMovie(ctx context.Context, tx *sql.Tx, id int64, userID int64) error {
+	query := `
+		UPDATE movies
+		SET version = version + 1, updated_at = getdate()
+		` + movieRevisionOutput("@p2", "updated_at") + `
+		WHERE id = @p1 AND deleted_at IS NULL;
+	`
+
+	result, err := tx.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return storeRevisionExternalIDs(ctx, tx, id)
+}
+
+// SetPoster records that a new poster of the movie has been uploaded, which changes its poster URL.
+// The version of the movie is increased and a revision is stored, like for every other change.
+func (m MovieModel) SetPoster(movie *Movie, userID int64) error {
+	query := `
+		UPDATE movies
+		SET poster_updated_at = getdate(), version = version + 1, updated_at = getdate()
+		` + movieRevisionOutput("@p2", "updated_at") + `
+		OUTPUT INSERTED.poster_updated_at, INSERTED.updated_at, INSERTED.version
+		WHERE id = @p1 AND deleted_at IS NULL;
+	`
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, movie.ID, userID).Scan(&movie.PosterUpdatedAt, &movie.UpdatedAt, &movie.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = storeRevisionExternalIDs(ctx, tx, movie.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		return err
 	}
 
+	movie.setPosterURL()
+
 	return nil
 }
 
+// Delete moves a movie to the trash. It can be restored until it is purged.
 func (m MovieModel) Delete(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return deleteMovie(ctx, m.DB, id)
+}
+
+// The deleteMovie() function moves a movie to the trash with either a *sql.DB or a *sql.Tx.
+func deleteMovie(ctx context.Context, q executor, id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
-		DELETE FROM movies
-		WHERE id = @p1
+		UPDATE movies
+		SET deleted_at = getdate()
+		WHERE id = @p1 AND deleted_at IS NULL
 	`
 
-	result, err := m.DB.Exec(query, id)
+	result, err := q.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -212,3 +1031,302 @@ func (m MovieModel) Delete(id int64) error {
 
 	return nil
 }
+
+// GetTrash returns a page of the movies which have been moved to the trash.
+func (m MovieModel) GetTrash(filters Filters) ([]*Movie, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), id, created_at, title, year, runtime, genres, status, release_date, version, deleted_at
+		FROM movies
+		WHERE deleted_at IS NOT NULL AND merged_into IS NULL
+		ORDER BY %s
+		OFFSET @p1 ROWS FETCH NEXT @p2 ROWS ONLY;
+	`, orderBy(filters.sortColumns("id")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, filters.offset(), filters.limit())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+
+		err := rows.Scan(
+			&totalRecords,
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			(*stringList)(&movie.Genres),
+			&movie.Status,
+			&movie.ReleaseDate,
+			&movie.Version,
+			&movie.DeletedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return movies, metadata, nil
+}
+
+// GetUpcoming returns the announced movies, ordered by their release date. Movies without a release date
+// come last.
+func (m MovieModel) GetUpcoming(filters Filters, preferred []string) ([]*Movie, Metadata, error) {
+	langs, err := languages(preferred)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	columns, dest := movieSelect(nil)
+
+	query := `
+		SELECT COUNT(*) OVER(), ` + columns + `
+		FROM movies
+		` + movieRatingsApply + `
+		` + movieTranslationApply("@p3") + `
+		WHERE movies.deleted_at IS NULL AND movies.status = 'announced'
+		ORDER BY CASE WHEN movies.release_date IS NULL THEN 1 ELSE 0 END, movies.release_date ASC, movies.id ASC
+		OFFSET @p1 ROWS FETCH NEXT @p2 ROWS ONLY;
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, filters.offset(), filters.limit(), langs)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+
+		err := rows.Scan(append([]any{&totalRecords}, dest(&movie)...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		movie.setPosterURL()
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return movies, metadata, nil
+}
+
+// Restore takes a movie out of the trash. The version is incremented, so clients
+// holding the version from before the deletion get an edit conflict.
+func (m MovieModel) Restore(id int64, userID int64) (*Movie, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		UPDATE movies
+		SET deleted_at = NULL, version = version + 1, updated_at = getdate()
+		` + movieRevisionOutput("@p2", "updated_at") + `
+		OUTPUT INSERTED.id, INSERTED.created_at, INSERTED.updated_at, INSERTED.title, INSERTED.year, INSERTED.runtime, INSERTED.genres,
+			INSERTED.status, INSERTED.release_date, INSERTED.version
+		WHERE id = @p1 AND deleted_at IS NOT NULL AND merged_into IS NULL;
+	`
+
+	var movie Movie
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, id, userID).Scan(
+		&movie.ID,
+		&movie.CreatedAt,
+		&movie.UpdatedAt,
+		&movie.Title,
+		&movie.Year,
+		&movie.Runtime,
+		(*stringList)(&movie.Genres),
+		&movie.Status,
+		&movie.ReleaseDate,
+		&movie.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = storeRevisionExternalIDs(ctx, tx, movie.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &movie, nil
+}
+
+// maxTrashRetention is the longest retention period of the trash which is passed to the database.
+const maxTrashRetention = 100 * 365 * 24 * time.Hour
+
+// Purge permanently removes all movies which have been in the trash for longer than the retention period.
+// It returns the IDs of the removed movies, so the caller can remove their stored files.
+func (m MovieModel) Purge(retention time.Duration) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	// The driver sends Go integers as bigint, which DATEADD() doesn't accept, so the minutes are cast to int.
+	// The retention is capped, as a longer one would lead to a date before the minimum of datetime (1753).
+	minutes := int64(min(retention, maxTrashRetention).Minutes())
+
+	// The revisions of the purged movies are removed first. Merged movies are kept as tombstones.
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM movie_revisions
+		WHERE movie_id IN (
+			SELECT id FROM movies
+			WHERE deleted_at IS NOT NULL AND deleted_at < DATEADD(minute, -CAST(@p1 AS int), getdate()) AND merged_into IS NULL
+		);
+	`, minutes)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		DELETE FROM movies
+		OUTPUT DELETED.id
+		WHERE deleted_at IS NOT NULL AND deleted_at < DATEADD(minute, -CAST(@p1 AS int), getdate()) AND merged_into IS NULL;
+	`, minutes)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	purged := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		purged = append(purged, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
+
+// BeginTx starts a transaction in which several movies are changed at once. The changes are only
+// stored if the transaction is committed.
+func (m MovieModel) BeginTx() (*MovieTx, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &MovieTx{tx: tx}, nil
+}
+
+// Get returns a movie within the transaction.
+func (t *MovieTx) Get(id int64) (*Movie, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getMovie(ctx, t.tx, id, nil, nil)
+}
+
+// Insert adds a movie within the transaction.
+func (t *MovieTx) Insert(movie *Movie, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return insertMovie(ctx, t.tx, movie, userID)
+}
+
+// Update stores the changes of a movie within the transaction.
+func (t *MovieTx) Update(movie *Movie, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return updateMovie(ctx, t.tx, movie, userID)
+}
+
+// Delete moves a movie to the trash within the transaction.
+func (t *MovieTx) Delete(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return deleteMovie(ctx, t.tx, id)
+}
+
+// Commit stores all changes of the transaction.
+func (t *MovieTx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback discards all changes of the transaction. It is safe to call Rollback after Commit.
+func (t *MovieTx) Rollback() error {
+	err := t.tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,220 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/comfortliner/greenlight/internal/validator"
+)
+
+// **********************
+// * Model Definition
+// **********************
+
+// Person struct to represent an individual member of the cast or crew of movies.
+type Person struct {
+	ID        int64     `json:"id"`                   // Unique integer ID for the person.
+	CreatedAt time.Time `json:"-"`                    // Timestamp for when the person is added to our database.
+	Name      string    `json:"name"`                 // Name of the person.
+	BirthYear int32     `json:"birth_year,omitempty"` // Year of birth, 0 if unknown.
+	Biography string    `json:"biography,omitempty"`  // Short biography.
+	Version   int32     `json:"version"`              // The version number starts at 1 and will be incremented each time the person is updated.
+}
+
+// PersonModel struct type which wraps a sql.DB connection pool.
+type PersonModel struct {
+	DB *sql.DB
+}
+
+// **********************
+// * Data Validation
+// **********************
+
+func ValidatePerson(v *validator.Validator, person *Person) {
+	v.Check(person.Name != "", "name", "must be provided")
+	v.Check(len(person.Name) <= 255, "name", "must not be more than 255 bytes long")
+
+	if person.BirthYear != 0 {
+		v.Check(person.BirthYear >= 1800, "birth_year", "must be greater than 1800")
+		v.Check(person.BirthYear <= int32(time.Now().Year()), "birth_year", "must not be in the future")
+	}
+
+	v.Check(len(person.Biography) <= 10_000, "biography", "must not be more than 10000 bytes long")
+}
+
+// **********************
+// * Data Manipulation
+// **********************
+
+func (m PersonModel) Insert(person *Person) error {
+	query := `
+		INSERT INTO people (name, birth_year, biography)
+		OUTPUT INSERTED.id, INSERTED.created_at, INSERTED.version
+		VALUES (@p1, @p2, @p3);
+	`
+
+	args := []any{
+		person.Name,
+		sql.NullInt32{Int32: person.BirthYear, Valid: person.BirthYear != 0},
+		person.Biography,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&person.ID, &person.CreatedAt, &person.Version)
+}
+
+func (m PersonModel) Get(id int64) (*Person, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, name, COALESCE(birth_year, 0), biography, version
+		FROM people
+		WHERE id = @p1;
+	`
+
+	var person Person
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&person.ID,
+		&person.CreatedAt,
+		&person.Name,
+		&person.BirthYear,
+		&person.Biography,
+		&person.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &person, nil
+}
+
+// GetAll returns a page of people whose name contains the given text.
+func (m PersonModel) GetAll(name string, filters Filters) ([]*Person, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), id, created_at, name, COALESCE(birth_year, 0), biography, version
+		FROM people
+		WHERE (@p1 = '' OR CHARINDEX(LOWER(@p1), LOWER(name)) > 0)
+		ORDER BY %s
+		OFFSET @p2 ROWS FETCH NEXT @p3 ROWS ONLY;
+	`, orderBy(filters.sortColumns("id")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.offset(), filters.limit())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	people := []*Person{}
+
+	for rows.Next() {
+		var person Person
+
+		err := rows.Scan(
+			&totalRecords,
+			&person.ID,
+			&person.CreatedAt,
+			&person.Name,
+			&person.BirthYear,
+			&person.Biography,
+			&person.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		people = append(people, &person)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return people, metadata, nil
+}
+
+func (m PersonModel) Update(person *Person) error {
+	query := `
+		UPDATE people
+		SET name = @p1, birth_year = @p2, biography = @p3, version = version + 1
+		OUTPUT INSERTED.version
+		WHERE id = @p4 AND version = @p5;
+	`
+
+	args := []any{
+		person.Name,
+		sql.NullInt32{Int32: person.BirthYear, Valid: person.BirthYear != 0},
+		person.Biography,
+		person.ID,
+		person.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&person.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a person together with all of their credits.
+func (m PersonModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM people
+		WHERE id = @p1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 )
 
@@ -74,15 +75,15 @@ func (m PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
 	return permissions, nil
 }
 
-func (m PermissionModel) AddForUser(userID int64, code string) error {
+func (m PermissionModel) AddForUser(userID int64, codes ...string) error {
 	query := `
 	INSERT INTO userpermissions
-	SELECT @p1, permissions.id FROM permissions WHERE code in (@p2);
+	SELECT @p1, permissions.id FROM permissions WHERE code in (SELECT value FROM STRING_SPLIT(@p2, ','));
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, userID, code)
+	_, err := m.DB.ExecContext(ctx, query, userID, strings.Join(codes, ","))
 	return err
 }
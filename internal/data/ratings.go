@@ -0,0 +1,218 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/comfortliner/greenlight/internal/validator"
+)
+
+// **********************
+// * Model Definition
+// **********************
+
+// Rating struct to represent the rating and review of a movie by a single user.
+type Rating struct {
+	MovieID   int64     `json:"movie_id"`         // ID of the rated movie.
+	UserID    int64     `json:"user_id"`          // ID of the user who rated the movie.
+	UserName  string    `json:"user_name"`        // Name of the user who rated the movie.
+	Rating    int8      `json:"rating"`           // Rating from 1 to 10.
+	Review    string    `json:"review,omitempty"` // Optional review text.
+	CreatedAt time.Time `json:"created_at"`       // Timestamp for when the movie has been rated first.
+	UpdatedAt time.Time `json:"updated_at"`       // Timestamp for when the rating has been changed last.
+}
+
+// RatingSummary struct to represent the distribution of the user ratings of a movie.
+type RatingSummary struct {
+	AverageRating float64        `json:"average_rating"` // Average of all user ratings, 0 if the movie hasn't been rated yet.
+	RatingCount   int64          `json:"rating_count"`   // Number of user ratings.
+	Distribution  map[int8]int64 `json:"distribution"`   // Number of user ratings per rating (1-10).
+}
+
+// RatingModel struct type which wraps a sql.DB connection pool.
+type RatingModel struct {
+	DB *sql.DB
+}
+
+// **********************
+// * Data Validation
+// **********************
+
+func ValidateRating(v *validator.Validator, rating *Rating) {
+	v.Check(rating.Rating >= 1, "rating", "must be at least 1")
+	v.Check(rating.Rating <= 10, "rating", "must not be more than 10")
+
+	v.Check(len(rating.Review) <= 10_000, "review", "must not be more than 10000 bytes long")
+}
+
+// **********************
+// * Data Manipulation
+// **********************
+
+// Upsert adds the rating of a user for a movie, or replaces it if the user already rated the movie.
+// HOLDLOCK makes the MERGE atomic, so two concurrent first ratings of a user can't both insert.
+func (m RatingModel) Upsert(rating *Rating) error {
+	query := `
+		MERGE movie_ratings WITH (HOLDLOCK) AS target
+		USING (SELECT @p1 AS movie_id, @p2 AS user_id) AS source
+		ON target.movie_id = source.movie_id AND target.user_id = source.user_id
+		WHEN MATCHED THEN
+			UPDATE SET rating = @p3, review = @p4, updated_at = getdate()
+		WHEN NOT MATCHED THEN
+			INSERT (movie_id, user_id, rating, review) VALUES (@p1, @p2, @p3, @p4)
+		OUTPUT INSERTED.created_at, INSERTED.updated_at;
+	`
+
+	args := []any{
+		rating.MovieID,
+		rating.UserID,
+		rating.Rating,
+		sql.NullString{String: rating.Review, Valid: rating.Review != ""},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&rating.CreatedAt, &rating.UpdatedAt)
+}
+
+// Delete removes the rating of a user for a movie.
+func (m RatingModel) Delete(movieID, userID int64) error {
+	query := `
+		DELETE FROM movie_ratings
+		WHERE movie_id = @p1 AND user_id = @p2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, movieID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAllForMovie returns a page of the ratings and reviews of a movie.
+func (m RatingModel) GetAllForMovie(movieID int64, filters Filters) ([]*Rating, Metadata, error) {
+	// The sort columns are qualified, as the users table has columns of the same name.
+	columns := filters.sortColumns("user_id")
+	for i := range columns {
+		columns[i].Name = "movie_ratings." + columns[i].Name
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), movie_ratings.movie_id, movie_ratings.user_id, users.name,
+			movie_ratings.rating, COALESCE(movie_ratings.review, ''), movie_ratings.created_at, movie_ratings.updated_at
+		FROM movie_ratings
+		INNER JOIN users ON users.id = movie_ratings.user_id
+		WHERE movie_ratings.movie_id = @p1
+		ORDER BY %s
+		OFFSET @p2 ROWS FETCH NEXT @p3 ROWS ONLY;
+	`, orderBy(columns))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filters.offset(), filters.limit())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	ratings := []*Rating{}
+
+	for rows.Next() {
+		var rating Rating
+
+		err := rows.Scan(
+			&totalRecords,
+			&rating.MovieID,
+			&rating.UserID,
+			&rating.UserName,
+			&rating.Rating,
+			&rating.Review,
+			&rating.CreatedAt,
+			&rating.UpdatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		ratings = append(ratings, &rating)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return ratings, metadata, nil
+}
+
+// GetSummaries returns the rating summaries of the given movies, keyed by the movie ID.
+func (m RatingModel) GetSummaries(movieIDs []int64) (map[int64]*RatingSummary, error) {
+	js, err := json.Marshal(movieIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT movie_id, rating, COUNT(*)
+		FROM movie_ratings
+		WHERE movie_id IN (SELECT CAST(value AS bigint) FROM OPENJSON(@p1))
+		GROUP BY movie_id, rating;
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, string(js))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	summaries := make(map[int64]*RatingSummary, len(movieIDs))
+	for _, id := range movieIDs {
+		summaries[id] = &RatingSummary{Distribution: map[int8]int64{}}
+	}
+
+	for rows.Next() {
+		var movieID, count int64
+		var rating int8
+
+		err := rows.Scan(&movieID, &rating, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		summary := summaries[movieID]
+		summary.Distribution[rating] = count
+		summary.AverageRating = (summary.AverageRating*float64(summary.RatingCount) + float64(rating)*float64(count)) / float64(summary.RatingCount+count)
+		summary.RatingCount += count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,257 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// **********************
+// * Model Definition
+// **********************
+
+// MovieRevision struct to represent the snapshot of a single version of a movie.
+type MovieRevision struct {
+	MovieID     int64    `json:"movie_id"`         // ID of the movie.
+	Version     int32    `json:"version"`          // Version of the movie this snapshot belongs to.
+	Title       string   `json:"title"`            // Movie title.
+	Year        int32    `json:"year,omitempty"`   // Movie release year.
+	Runtime     int32    `json:"runtime"`          // Movie runtime (in minutes).
+	Genres      []string `json:"genres,omitempty"` // Slice of genres for the movie.
+	Status      *string  `json:"status,omitempty"` // Production status, nil if the revision predates the status.
+	ReleaseDate *Date    `json:"release_date"`     // Release date, if known.
+
+	OriginalLanguage    *string           `json:"original_language,omitempty"`    // ISO 639-1 code of the original language, nil if the revision predates the metadata.
+	ProductionCountries []string          `json:"production_countries,omitempty"` // ISO 3166-1 codes of the production countries.
+	SpokenLanguages     []string          `json:"spoken_languages,omitempty"`     // ISO 639-1 codes of the spoken languages.
+	Certifications      map[string]string `json:"certifications,omitempty"`       // Age ratings by country.
+	Plot                string            `json:"plot,omitempty"`                 // Longer description of the story.
+	ExternalIDs         map[string]string `json:"external_ids,omitempty"`         // Identifiers in other databases by source.
+
+	UserID    *int64    `json:"user_id"`    // ID of the user who created this version, if known.
+	CreatedAt time.Time `json:"created_at"` // Timestamp for when this version has been created.
+}
+
+// FieldChange struct to represent the difference of a single field between two versions of a movie.
+type FieldChange struct {
+	Revision any `json:"revision"`
+	Current  any `json:"current"`
+}
+
+// MovieRevisionModel struct type which wraps a sql.DB connection pool.
+type MovieRevisionModel struct {
+	DB *sql.DB
+}
+
+// Diff returns the fields which differ between the revision and the given (current) movie, keyed by
+// their JSON field name.
+func (rev *MovieRevision) Diff(movie *Movie) map[string]FieldChange {
+	diff := make(map[string]FieldChange)
+
+	if rev.Title != movie.Title {
+		diff["title"] = FieldChange{Revision: rev.Title, Current: movie.Title}
+	}
+
+	if rev.Year != movie.Year {
+		diff["year"] = FieldChange{Revision: rev.Year, Current: movie.Year}
+	}
+
+	if rev.Runtime != movie.Runtime {
+		diff["runtime"] = FieldChange{Revision: rev.Runtime, Current: movie.Runtime}
+	}
+
+	if !slices.Equal(rev.Genres, movie.Genres) {
+		diff["genres"] = FieldChange{Revision: rev.Genres, Current: movie.Genres}
+	}
+
+	// Revisions which predate the status don't record it, nor the release date.
+	if rev.Status != nil {
+		if *rev.Status != movie.Status {
+			diff["status"] = FieldChange{Revision: *rev.Status, Current: movie.Status}
+		}
+
+		if !equalDates(rev.ReleaseDate, movie.ReleaseDate) {
+			diff["release_date"] = FieldChange{Revision: rev.ReleaseDate, Current: movie.ReleaseDate}
+		}
+	}
+
+	// Revisions which predate the catalogue metadata don't record it, nor the external IDs.
+	if rev.OriginalLanguage != nil {
+		if *rev.OriginalLanguage != movie.OriginalLanguage {
+			diff["original_language"] = FieldChange{Revision: *rev.OriginalLanguage, Current: movie.OriginalLanguage}
+		}
+
+		if !slices.Equal(rev.ProductionCountries, movie.ProductionCountries) {
+			diff["production_countries"] = FieldChange{Revision: rev.ProductionCountries, Current: movie.ProductionCountries}
+		}
+
+		if !slices.Equal(rev.SpokenLanguages, movie.SpokenLanguages) {
+			diff["spoken_languages"] = FieldChange{Revision: rev.SpokenLanguages, Current: movie.SpokenLanguages}
+		}
+
+		if !maps.Equal(rev.Certifications, movie.Certifications) {
+			diff["certifications"] = FieldChange{Revision: rev.Certifications, Current: movie.Certifications}
+		}
+
+		if rev.Plot != movie.Plot {
+			diff["plot"] = FieldChange{Revision: rev.Plot, Current: movie.Plot}
+		}
+
+		if !maps.Equal(rev.ExternalIDs, movie.ExternalIDs) {
+			diff["external_ids"] = FieldChange{Revision: rev.ExternalIDs, Current: movie.ExternalIDs}
+		}
+	}
+
+	return diff
+}
+
+// Apply copies the values of the revision to the movie. The version of the movie is left unchanged, as are
+// the fields which the revision doesn't record.
+func (rev *MovieRevision) Apply(movie *Movie) {
+	movie.Title = rev.Title
+	movie.Year = rev.Year
+	movie.Runtime = rev.Runtime
+	movie.Genres = slices.Clone(rev.Genres)
+
+	if rev.Status != nil {
+		movie.Status = *rev.Status
+		movie.ReleaseDate = rev.ReleaseDate
+	}
+
+	if rev.OriginalLanguage != nil {
+		movie.OriginalLanguage = *rev.OriginalLanguage
+		movie.ProductionCountries = slices.Clone(rev.ProductionCountries)
+		movie.SpokenLanguages = slices.Clone(rev.SpokenLanguages)
+		movie.Certifications = maps.Clone(rev.Certifications)
+		movie.Plot = rev.Plot
+		movie.ExternalIDs = maps.Clone(rev.ExternalIDs)
+	}
+}
+
+// The equalDates() function reports whether two optional dates are both missing or the same day.
+func equalDates(a, b *Date) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(b.Time)
+}
+
+// **********************
+// * Data Manipulation
+// **********************
+
+// GetAllForMovie returns a page of the revisions of a movie.
+func (m MovieRevisionModel) GetAllForMovie(movieID int64, filters Filters) ([]*MovieRevision, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), movie_id, version, title, year, runtime, genres, status, release_date,
+			original_language, production_countries, spoken_languages, certifications, COALESCE(plot, ''), external_ids, user_id, created_at
+		FROM movie_revisions
+		WHERE movie_id = @p1
+		ORDER BY %s
+		OFFSET @p2 ROWS FETCH NEXT @p3 ROWS ONLY;
+	`, orderBy(filters.sortColumns("version")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filters.offset(), filters.limit())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	revisions := []*MovieRevision{}
+
+	for rows.Next() {
+		var revision MovieRevision
+
+		err := rows.Scan(
+			&totalRecords,
+			&revision.MovieID,
+			&revision.Version,
+			&revision.Title,
+			&revision.Year,
+			&revision.Runtime,
+			(*stringList)(&revision.Genres),
+			&revision.Status,
+			&revision.ReleaseDate,
+			&revision.OriginalLanguage,
+			(*stringList)(&revision.ProductionCountries),
+			(*stringList)(&revision.SpokenLanguages),
+			(*stringMap)(&revision.Certifications),
+			&revision.Plot,
+			(*stringMap)(&revision.ExternalIDs),
+			&revision.UserID,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return revisions, metadata, nil
+}
+
+// Get returns a single revision of a movie.
+func (m MovieRevisionModel) Get(movieID int64, version int32) (*MovieRevision, error) {
+	if movieID < 1 || version < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT movie_id, version, title, year, runtime, genres, status, release_date,
+			original_language, production_countries, spoken_languages, certifications, COALESCE(plot, ''), external_ids, user_id, created_at
+		FROM movie_revisions
+		WHERE movie_id = @p1 AND version = @p2;
+	`
+
+	var revision MovieRevision
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, version).Scan(
+		&revision.MovieID,
+		&revision.Version,
+		&revision.Title,
+		&revision.Year,
+		&revision.Runtime,
+		(*stringList)(&revision.Genres),
+		&revision.Status,
+		&revision.ReleaseDate,
+		&revision.OriginalLanguage,
+		(*stringList)(&revision.ProductionCountries),
+		(*stringList)(&revision.SpokenLanguages),
+		(*stringMap)(&revision.Certifications),
+		&revision.Plot,
+		(*stringMap)(&revision.ExternalIDs),
+		&revision.UserID,
+		&revision.CreatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
@@ -0,0 +1,57 @@
+package data
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// minSimilarity is the share of trigrams a search term must have in common with a title
+// to be treated as a match if the term isn't a substring of the title.
+const minSimilarity = 0.5
+
+// searchTerm struct represents a single word of a title search together with its trigrams.
+type searchTerm struct {
+	Term     string   `json:"term"`
+	Trigrams []string `json:"trigrams"`
+}
+
+// The trigrams() function returns the sorted, distinct trigrams of a text. Like the pg_trgm extension of
+// PostgreSQL each lower-cased word is padded with two spaces in front and one space at the end.
+func trigrams(s string) []string {
+	set := make(map[string]bool)
+
+	for _, word := range strings.Fields(strings.ToLower(s)) {
+		r := []rune("  " + word + " ")
+
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = true
+		}
+	}
+
+	result := make([]string, 0, len(set))
+	for trigram := range set {
+		result = append(result, trigram)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// The searchTerms() function splits a title search into its words and returns them, together with their
+// trigrams, as JSON array which can be read by OPENJSON() in a query.
+func searchTerms(title string) (string, error) {
+	terms := []searchTerm{}
+
+	for _, word := range strings.Fields(strings.ToLower(title)) {
+		terms = append(terms, searchTerm{Term: word, Trigrams: trigrams(word)})
+	}
+
+	js, err := json.Marshal(terms)
+	if err != nil {
+		return "", err
+	}
+
+	return string(js), nil
+}
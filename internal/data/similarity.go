@@ -0,0 +1,335 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"time"
+)
+
+// Weights of the parts of the similarity score. The co-rating part is left out (and the other weights
+// scaled up) if the two movies don't have enough common raters.
+const (
+	genreWeight    = 0.4
+	yearWeight     = 0.2
+	runtimeWeight  = 0.1
+	coRatingWeight = 0.3
+
+	// Release years and runtimes which are this far apart (or further) don't add to the score.
+	maxYearDistance    = 20
+	maxRuntimeDistance = 60
+
+	// minCoRaters is the number of users which must have rated both movies for the co-rating part.
+	minCoRaters = 2
+
+	// listEntryWeight is the interest in a movie which is in one of the lists of a user but hasn't been rated.
+	listEntryWeight = 0.5
+)
+
+// ScoredMovie struct is a movie together with its similarity or recommendation score (0-1).
+type ScoredMovie struct {
+	Movie *Movie  `json:"movie"`
+	Score float64 `json:"score"`
+}
+
+// SimilarityIndex struct holds the movies, ratings and list entries which are needed to find similar
+// movies and recommendations in memory. It is built by MovieModel.BuildSimilarityIndex() and is read-only
+// afterwards, so it can be used by many goroutines at once.
+type SimilarityIndex struct {
+	builtAt time.Time
+	movies  map[int64]*Movie
+	ids     []int64
+
+	// Ratings of every movie by user, centered on the average rating of the user.
+	ratings map[int64]map[int64]float64
+
+	// Interest of every user in the movies they have rated or added to a list (-1 to 1).
+	interests map[int64]map[int64]float64
+}
+
+// BuildSimilarityIndex reads all movies which aren't in the trash, together with their ratings and
+// list entries, and builds a new similarity index. The reads don't block concurrent changes, so the ratings
+// and list entries may refer to movies which have been created or deleted meanwhile. These are skipped when
+// the index is used.
+func (m MovieModel) BuildSimilarityIndex() (*SimilarityIndex, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	index := &SimilarityIndex{
+		builtAt:   time.Now(),
+		movies:    make(map[int64]*Movie),
+		ratings:   make(map[int64]map[int64]float64),
+		interests: make(map[int64]map[int64]float64),
+	}
+
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT movies.id, movies.created_at, movies.title, movies.year, movies.runtime, movies.genres, movies.version,
+			COALESCE(ratings.average_rating, 0), COALESCE(ratings.rating_count, 0), movies.poster_updated_at
+		FROM movies
+		`+movieRatingsApply+`
+		WHERE movies.deleted_at IS NULL
+		ORDER BY movies.id ASC;
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var movie Movie
+
+		err := rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			(*stringList)(&movie.Genres),
+			&movie.Version,
+			&movie.AverageRating,
+			&movie.RatingCount,
+			&movie.PosterUpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		movie.setPosterURL()
+
+		index.movies[movie.ID] = &movie
+		index.ids = append(index.ids, movie.ID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The ratings are centered on the average rating of each user, so a movie a user rated below their
+	// average counts as disliked.
+	rows, err = m.DB.QueryContext(ctx, `
+		SELECT movie_ratings.movie_id, movie_ratings.user_id, movie_ratings.rating,
+			AVG(CAST(movie_ratings.rating AS float)) OVER(PARTITION BY movie_ratings.user_id)
+		FROM movie_ratings
+		INNER JOIN movies ON movies.id = movie_ratings.movie_id
+		WHERE movies.deleted_at IS NULL;
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID, userID int64
+		var rating int8
+		var average float64
+
+		err := rows.Scan(&movieID, &userID, &rating, &average)
+		if err != nil {
+			return nil, err
+		}
+
+		if index.ratings[movieID] == nil {
+			index.ratings[movieID] = make(map[int64]float64)
+		}
+		index.ratings[movieID][userID] = float64(rating) - average
+
+		// Ratings from 1 to 10 are mapped to an interest from -1 to 1.
+		index.interest(userID)[movieID] = (float64(rating) - 5.5) / 4.5
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = m.DB.QueryContext(ctx, `
+		SELECT DISTINCT movie_lists.user_id, movie_list_entries.movie_id
+		FROM movie_list_entries
+		INNER JOIN movie_lists ON movie_lists.id = movie_list_entries.list_id
+		INNER JOIN movies ON movies.id = movie_list_entries.movie_id
+		WHERE movies.deleted_at IS NULL;
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID, movieID int64
+
+		err := rows.Scan(&userID, &movieID)
+		if err != nil {
+			return nil, err
+		}
+
+		// A rating says more about the interest of a user than a list entry.
+		if _, rated := index.interest(userID)[movieID]; !rated {
+			index.interests[userID][movieID] = listEntryWeight
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+func (idx *SimilarityIndex) interest(userID int64) map[int64]float64 {
+	if idx.interests[userID] == nil {
+		idx.interests[userID] = make(map[int64]float64)
+	}
+
+	return idx.interests[userID]
+}
+
+// BuiltAt returns the time the index has been built.
+func (idx *SimilarityIndex) BuiltAt() time.Time {
+	return idx.builtAt
+}
+
+// Similar returns a page of the movies which are most similar to the given movie, ordered by their score.
+// The page is selected with the page and page size of the filters.
+func (idx *SimilarityIndex) Similar(movieID int64, filters Filters) ([]*ScoredMovie, Metadata, error) {
+	movie, ok := idx.movies[movieID]
+	if !ok {
+		return nil, Metadata{}, ErrRecordNotFound
+	}
+
+	scored := []*ScoredMovie{}
+
+	for _, id := range idx.ids {
+		if id == movieID {
+			continue
+		}
+
+		score := idx.similarity(movie, idx.movies[id])
+		if score > 0 {
+			scored = append(scored, &ScoredMovie{Movie: idx.movies[id], Score: score})
+		}
+	}
+
+	movies, metadata := paginateScored(scored, filters)
+
+	return movies, metadata, nil
+}
+
+// Recommend returns a page of the movies which are recommended to a user, ordered by their score. The score
+// of a movie is the average similarity to the movies the user has rated or added to a list, weighted with
+// the interest of the user in these movies. Movies the user already knows are left out.
+func (idx *SimilarityIndex) Recommend(userID int64, filters Filters) ([]*ScoredMovie, Metadata) {
+	interests := idx.interests[userID]
+
+	scored := []*ScoredMovie{}
+
+	for _, id := range idx.ids {
+		if _, known := interests[id]; known {
+			continue
+		}
+
+		var sum, weights float64
+
+		for knownID, interest := range interests {
+			known, ok := idx.movies[knownID]
+			if !ok {
+				continue
+			}
+
+			sum += interest * idx.similarity(idx.movies[id], known)
+			weights += math.Abs(interest)
+		}
+
+		if weights > 0 && sum > 0 {
+			scored = append(scored, &ScoredMovie{Movie: idx.movies[id], Score: sum / weights})
+		}
+	}
+
+	return paginateScored(scored, filters)
+}
+
+// The similarity() method returns the similarity score (0-1) of two movies. It combines the shared genres,
+// the distance of the release years and runtimes, and how similar the movies have been rated by the same users.
+func (idx *SimilarityIndex) similarity(a, b *Movie) float64 {
+	score := genreWeight*jaccard(a.Genres, b.Genres) +
+		yearWeight*proximity(a.Year, b.Year, maxYearDistance) +
+		runtimeWeight*proximity(a.Runtime, b.Runtime, maxRuntimeDistance)
+	weights := genreWeight + yearWeight + runtimeWeight
+
+	if coRating, ok := cosine(idx.ratings[a.ID], idx.ratings[b.ID]); ok {
+		// The cosine similarity (-1 to 1) is mapped to 0-1.
+		score += coRatingWeight * (coRating + 1) / 2
+		weights += coRatingWeight
+	}
+
+	return score / weights
+}
+
+// The jaccard() function returns the share of the genres two movies have in common.
+func jaccard(a, b []string) float64 {
+	shared := 0
+
+	for _, genre := range a {
+		if slices.Contains(b, genre) {
+			shared++
+		}
+	}
+
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+
+	return float64(shared) / float64(union)
+}
+
+// The proximity() function returns 1 for equal values, decreasing linearly to 0 at the maximum distance.
+func proximity(a, b int32, maxDistance float64) float64 {
+	return max(0, 1-math.Abs(float64(a-b))/maxDistance)
+}
+
+// The cosine() function returns the cosine similarity of the (centered) ratings of two movies by the users
+// which have rated both movies. It reports false if there are less than minCoRaters of these users.
+func cosine(a, b map[int64]float64) (float64, bool) {
+	var dot, normA, normB float64
+	common := 0
+
+	for userID, x := range a {
+		y, ok := b[userID]
+		if !ok {
+			continue
+		}
+
+		dot += x * y
+		normA += x * x
+		normB += y * y
+		common++
+	}
+
+	if common < minCoRaters || normA == 0 || normB == 0 {
+		return 0, false
+	}
+
+	return dot / math.Sqrt(normA*normB), true
+}
+
+// The paginateScored() function orders scored movies by their score (and ID for equal scores) and returns
+// the page selected by the filters.
+func paginateScored(scored []*ScoredMovie, filters Filters) ([]*ScoredMovie, Metadata) {
+	slices.SortFunc(scored, func(a, b *ScoredMovie) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+
+		return cmp.Compare(a.Movie.ID, b.Movie.ID)
+	})
+
+	metadata := calculateMetadata(len(scored), filters.Page, filters.PageSize)
+
+	start := min(filters.offset(), len(scored))
+	end := min(start+filters.limit(), len(scored))
+
+	return scored[start:end], metadata
+}
@@ -0,0 +1,116 @@
+package data
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// runtimePercentiles holds the percentiles of the runtime distribution which are returned in the statistics.
+var runtimePercentiles = []int{25, 50, 75, 90, 95}
+
+// MovieStats struct holds statistics about the movies of the catalogue.
+type MovieStats struct {
+	TotalMovies  int            `json:"total_movies"`   // Number of movies.
+	TotalRuntime int64          `json:"total_runtime"`  // Sum of the known runtimes of all movies (in minutes).
+	ByYear       map[string]int `json:"by_year"`        // Number of movies per release year.
+	ByDecade     map[string]int `json:"by_decade"`      // Number of movies per release decade, e.g. "1980s".
+	Runtime      RuntimeStats   `json:"runtime"`        // Distribution of the runtimes.
+	Genres       map[string]int `json:"genres"`         // Number of movies per genre.
+	AddedPerWeek []WeekCount    `json:"added_per_week"` // Number of movies added per ISO week, ordered by week.
+	GeneratedAt  time.Time      `json:"generated_at"`   // Timestamp for when the statistics have been computed.
+}
+
+// RuntimeStats struct describes the distribution of the runtimes of movies (in minutes). Movies without a
+// runtime (e.g. announced movies) are left out.
+type RuntimeStats struct {
+	Min         int32              `json:"min"`
+	Max         int32              `json:"max"`
+	Average     float64            `json:"average"`
+	Percentiles map[string]float64 `json:"percentiles"` // Keyed by "p25", "p50", ...
+}
+
+// WeekCount struct holds the number of movies added in an ISO week, e.g. "2024-W05".
+type WeekCount struct {
+	Week  string `json:"week"`
+	Count int    `json:"count"`
+}
+
+// GetStats computes the statistics of all movies matching the query. The movies are streamed from the
+// database, only their runtimes are held in memory to compute the percentiles.
+func (m MovieModel) GetStats(mq MovieQuery) (*MovieStats, error) {
+	stats := &MovieStats{
+		ByYear:   make(map[string]int),
+		ByDecade: make(map[string]int),
+		Runtime:  RuntimeStats{Percentiles: make(map[string]float64)},
+		Genres:   make(map[string]int),
+	}
+
+	mq.Fields = []string{"year", "runtime", "genres"}
+	filters := Filters{Sort: "id", SortSafelist: []string{"id"}}
+
+	runtimes := []int32{}
+	weeks := make(map[string]int)
+
+	err := m.Stream(mq, filters, func(movie *Movie) error {
+		stats.TotalMovies++
+
+		stats.ByYear[strconv.Itoa(int(movie.Year))]++
+		stats.ByDecade[fmt.Sprintf("%ds", movie.Year/10*10)]++
+
+		for _, genre := range movie.Genres {
+			stats.Genres[genre]++
+		}
+
+		year, week := movie.CreatedAt.ISOWeek()
+		weeks[fmt.Sprintf("%04d-W%02d", year, week)]++
+
+		if movie.Runtime > 0 {
+			stats.TotalRuntime += int64(movie.Runtime)
+			runtimes = append(runtimes, movie.Runtime)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(runtimes) > 0 {
+		slices.Sort(runtimes)
+
+		stats.Runtime.Min = runtimes[0]
+		stats.Runtime.Max = runtimes[len(runtimes)-1]
+		stats.Runtime.Average = float64(stats.TotalRuntime) / float64(len(runtimes))
+
+		for _, p := range runtimePercentiles {
+			stats.Runtime.Percentiles[fmt.Sprintf("p%d", p)] = percentile(runtimes, p)
+		}
+	}
+
+	stats.AddedPerWeek = make([]WeekCount, 0, len(weeks))
+	for week, count := range weeks {
+		stats.AddedPerWeek = append(stats.AddedPerWeek, WeekCount{Week: week, Count: count})
+	}
+
+	slices.SortFunc(stats.AddedPerWeek, func(a, b WeekCount) int {
+		return cmp.Compare(a.Week, b.Week)
+	})
+
+	stats.GeneratedAt = time.Now()
+
+	return stats, nil
+}
+
+// The percentile() function returns the p-th percentile of sorted values, interpolating linearly
+// between the two closest values.
+func percentile(sorted []int32, p int) float64 {
+	rank := float64(p) / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	return float64(sorted[lower]) + (rank-float64(lower))*float64(sorted[upper]-sorted[lower])
+}
@@ -56,20 +56,30 @@ func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error
 		Scope:  scope,
 	}
 
-	randomBytes := make([]byte, 16)
-
-	_, err := rand.Read(randomBytes)
+	plaintext, err := randomString()
 	if err != nil {
 		return nil, err
 	}
 
-	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	token.Plaintext = plaintext
 	hash := sha256.Sum256([]byte(token.Plaintext))
 	token.Hash = hash[:]
 
 	return token, nil
 }
 
+// randomString returns a 26 characters long, base32 encoded string of 16 cryptographically secure random bytes.
+func randomString() (string, error) {
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes), nil
+}
+
 // Insert adds the data for specific token to the token table.
 func (m TokenModel) Insert(token *Token) error {
 	query := `
@@ -0,0 +1,207 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/comfortliner/greenlight/internal/validator"
+)
+
+// LanguageRX matches (lower-cased) language tags like "de" or "en-us".
+var LanguageRX = regexp.MustCompile("^[a-z]{2,3}(-[a-z0-9]{2,8})*$")
+
+// **********************
+// * Model Definition
+// **********************
+
+// MovieTranslation struct to represent the title and overview of a movie in another language.
+type MovieTranslation struct {
+	MovieID   int64     `json:"movie_id"`           // ID of the translated movie.
+	Language  string    `json:"language"`           // Lower-cased language tag, e.g. "de" or "en-us".
+	Title     string    `json:"title"`              // Translated title of the movie.
+	Overview  string    `json:"overview,omitempty"` // Translated overview of the movie.
+	CreatedAt time.Time `json:"created_at"`         // Timestamp for when the translation has been added.
+	UpdatedAt time.Time `json:"updated_at"`         // Timestamp for when the translation has been changed last.
+}
+
+// MovieTranslationModel struct type which wraps a sql.DB connection pool.
+type MovieTranslationModel struct {
+	DB *sql.DB
+}
+
+// **********************
+// * Data Validation
+// **********************
+
+func ValidateMovieTranslation(v *validator.Validator, translation *MovieTranslation) {
+	v.Check(validator.Matches(translation.Language, LanguageRX), "language", "must be a valid language tag")
+	v.Check(len(translation.Language) <= 35, "language", "must not be more than 35 bytes long")
+
+	v.Check(translation.Title != "", "title", "must be provided")
+	v.Check(len(translation.Title) <= 500, "title", "must not be more then 500 bytes long")
+
+	v.Check(len(translation.Overview) <= 10_000, "overview", "must not be more than 10000 bytes long")
+}
+
+// **********************
+// * Data Manipulation
+// **********************
+
+// Upsert adds the translation of a movie, or replaces it if the movie has already been translated
+// to the language. The trigrams of the title are stored to find the movie by its translated title.
+// HOLDLOCK makes the MERGE atomic, so two concurrent first translations to a language can't both insert.
+// The version of the movie is increased, as the translation is part of its representation.
+func (m MovieTranslationModel) Upsert(translation *MovieTranslation, userID int64) error {
+	query := `
+		MERGE movie_translations WITH (HOLDLOCK) AS target
+		USING (SELECT @p1 AS movie_id, @p2 AS language) AS source
+		ON target.movie_id = source.movie_id AND target.language = source.language
+		WHEN MATCHED THEN
+			UPDATE SET title = @p3, overview = @p4, title_trigrams = @p5, updated_at = getdate()
+		WHEN NOT MATCHED THEN
+			INSERT (movie_id, language, title, overview, title_trigrams) VALUES (@p1, @p2, @p3, @p4, @p5)
+		OUTPUT INSERTED.created_at, INSERTED.updated_at;
+	`
+
+	args := []any{
+		translation.MovieID,
+		translation.Language,
+		translation.Title,
+		translation.Overview,
+		stringList(trigrams(translation.Title)),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&translation.CreatedAt, &translation.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = touchMovie(ctx, tx, translation.MovieID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes the translation of a movie to a language and increases the version of the movie.
+func (m MovieTranslationModel) Delete(movieID int64, language string, userID int64) error {
+	query := `
+		DELETE FROM movie_translations
+		WHERE movie_id = @p1 AND language = @p2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, movieID, language)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	err = touchMovie(ctx, tx, movieID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAllForMovie returns all translations of a movie, ordered by their language.
+func (m MovieTranslationModel) GetAllForMovie(movieID int64) ([]*MovieTranslation, error) {
+	query := `
+		SELECT movie_id, language, title, overview, created_at, updated_at
+		FROM movie_translations
+		WHERE movie_id = @p1
+		ORDER BY language ASC;
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	translations := []*MovieTranslation{}
+
+	for rows.Next() {
+		var translation MovieTranslation
+
+		err := rows.Scan(
+			&translation.MovieID,
+			&translation.Language,
+			&translation.Title,
+			&translation.Overview,
+			&translation.CreatedAt,
+			&translation.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		translations = append(translations, &translation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return translations, nil
+}
+
+// The languages() function returns the preferred (lower-cased) languages as JSON array which can be read by
+// OPENJSON() in a query. Every regional language ("de-ch") is followed by its base language ("de"), unless
+// the base language is listed on its own.
+func languages(preferred []string) (string, error) {
+	expanded := []string{}
+
+	for _, language := range preferred {
+		expanded = append(expanded, language)
+
+		if base, _, found := strings.Cut(language, "-"); found && !slices.Contains(preferred, base) {
+			expanded = append(expanded, base)
+		}
+	}
+
+	js, err := json.Marshal(expanded)
+	if err != nil {
+		return "", err
+	}
+
+	return string(js), nil
+}
@@ -0,0 +1,129 @@
+package data
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// stringList maps a slice of strings to a JSON array which is stored in a nvarchar(max) column.
+// SQL Server doesn't support array types, but JSON columns can be queried with OPENJSON().
+type stringList []string
+
+// The Value() method implements the driver.Valuer interface. An empty list is stored as NULL.
+func (s stringList) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	js, err := json.Marshal([]string(s))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(js), nil
+}
+
+// The Scan() method implements the sql.Scanner interface. A NULL value is scanned into a nil slice.
+func (s *stringList) Scan(src any) error {
+	var js []byte
+
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case string:
+		js = []byte(v)
+	case []byte:
+		js = v
+	default:
+		return fmt.Errorf("cannot scan %T into stringList", src)
+	}
+
+	return json.Unmarshal(js, (*[]string)(s))
+}
+
+// Date is a calendar date without time of day, e.g. the release date of a movie. It is encoded as
+// "2006-01-02" in JSON and stored in a date column.
+type Date struct {
+	time.Time
+}
+
+// dateLayout is the layout of a date in JSON.
+const dateLayout = "2006-01-02"
+
+// The MarshalJSON() method implements the json.Marshaler interface.
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format(dateLayout))
+}
+
+// The UnmarshalJSON() method implements the json.Unmarshaler interface.
+func (d *Date) UnmarshalJSON(js []byte) error {
+	var s string
+
+	err := json.Unmarshal(js, &s)
+	if err != nil {
+		return err
+	}
+
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, must be formatted as YYYY-MM-DD", s)
+	}
+
+	d.Time = t
+	return nil
+}
+
+// The Value() method implements the driver.Valuer interface.
+func (d Date) Value() (driver.Value, error) {
+	return d.Time, nil
+}
+
+// The Scan() method implements the sql.Scanner interface.
+func (d *Date) Scan(src any) error {
+	t, ok := src.(time.Time)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into Date", src)
+	}
+
+	d.Time = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return nil
+}
+
+// stringMap maps a map of strings to a JSON object which is stored in a nvarchar(max) column.
+type stringMap map[string]string
+
+// The Value() method implements the driver.Valuer interface. An empty map is stored as NULL.
+func (s stringMap) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	js, err := json.Marshal(map[string]string(s))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(js), nil
+}
+
+// The Scan() method implements the sql.Scanner interface. A NULL value is scanned into a nil map.
+func (s *stringMap) Scan(src any) error {
+	var js []byte
+
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case string:
+		js = []byte(v)
+	case []byte:
+		js = v
+	default:
+		return fmt.Errorf("cannot scan %T into stringMap", src)
+	}
+
+	return json.Unmarshal(js, (*map[string]string)(s))
+}
@@ -0,0 +1,394 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrTestFailed is returned by Apply() if the value of a "test" operation doesn't match the document.
+var ErrTestFailed = errors.New("test operation failed")
+
+// Operation struct is a single operation of a JSON Patch (RFC 6902) document.
+type Operation struct {
+	Op    string          `json:"op"`             // One of add, remove, replace, move, copy or test.
+	Path  string          `json:"path"`           // JSON Pointer (RFC 6901) of the target location.
+	From  string          `json:"from,omitempty"` // JSON Pointer of the source location of move and copy.
+	Value json.RawMessage `json:"value"`          // Value of add, replace and test, nil if it is missing.
+}
+
+// Merge applies a JSON Merge Patch (RFC 7396) to a JSON document and returns the patched document.
+// Members with a null value are removed, objects are merged recursively and all other values are replaced.
+func Merge(doc []byte, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target any, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = mergeValue(t[key], value)
+		}
+	}
+
+	return t
+}
+
+// Apply applies the operations of a JSON Patch (RFC 6902) to a JSON document and returns the patched document.
+// The operations are applied in order, and the first failing operation stops the patch. A failed "test"
+// operation returns an error wrapping ErrTestFailed.
+func Apply(doc []byte, operations []Operation) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, operation := range operations {
+		target, err = apply(target, operation)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %q): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+// The apply() function applies a single operation to a decoded document.
+func apply(doc any, operation Operation) (any, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, errors.New("value must be provided")
+		}
+
+		value, err := decode(operation.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		switch operation.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+
+			if !equal(current, value) {
+				return nil, ErrTestFailed
+			}
+
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+
+		var value any
+
+		if operation.Op == "move" {
+			if len(path) > len(from) && slices.Equal(path[:len(from)], from) {
+				return nil, errors.New("a value can't be moved into one of its children")
+			}
+
+			doc, value, err = remove(doc, from)
+		} else {
+			value, err = get(doc, from)
+			if err == nil {
+				value, err = clone(value)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return add(doc, path, value)
+	default:
+		return nil, errors.New("unknown operation")
+	}
+}
+
+// The parsePointer() function splits a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+// The empty pointer references the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = unescape.Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// The index() function converts a reference token into an array index, which must be less than length.
+func index(token string, length int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	if i >= length {
+		return 0, fmt.Errorf("array index %d is out of bounds", i)
+	}
+
+	return i, nil
+}
+
+// The update() function walks down a document to the parent of the last reference token of a path and replaces
+// the parent with the result of fn, which gets the parent and the last token.
+func update(doc any, path []string, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("member %q does not exist", path[0])
+		}
+
+		child, err := update(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		node[path[0]] = child
+		return node, nil
+	case []any:
+		i, err := index(path[0], len(node))
+		if err != nil {
+			return nil, err
+		}
+
+		child, err := update(node[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		node[i] = child
+		return node, nil
+	default:
+		return nil, fmt.Errorf("%q is neither an object nor an array", path[0])
+	}
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			doc = child
+		case []any:
+			i, err := index(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%q is neither an object nor an array", token)
+		}
+	}
+
+	return doc, nil
+}
+
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			if token == "-" {
+				return append(node, value), nil
+			}
+
+			i, err := index(token, len(node)+1)
+			if err != nil {
+				return nil, err
+			}
+
+			return slices.Insert(node, i, value), nil
+		default:
+			return nil, errors.New("the parent is neither an object nor an array")
+		}
+	})
+}
+
+func replace(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+
+			node[token] = value
+			return node, nil
+		case []any:
+			i, err := index(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+
+			node[i] = value
+			return node, nil
+		default:
+			return nil, errors.New("the parent is neither an object nor an array")
+		}
+	})
+}
+
+// The remove() function removes the value at a path and returns the document together with the removed value.
+func remove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.New("the whole document can't be removed")
+	}
+
+	var removed any
+
+	doc, err := update(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []any:
+			i, err := index(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+
+			removed = node[i]
+			return slices.Delete(node, i, i+1), nil
+		default:
+			return nil, errors.New("the parent is neither an object nor an array")
+		}
+	})
+
+	return doc, removed, err
+}
+
+// The equal() function compares two decoded values. Numbers are compared by their value,
+// so 1 and 1.0 are equal.
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+
+		return true
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, equal)
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		x, errA := a.Float64()
+		y, errB := b.Float64()
+		return errA == nil && errB == nil && x == y
+	default:
+		return a == b
+	}
+}
+
+// The decode() function decodes a JSON value. Numbers are decoded as json.Number, so large integers keep
+// their precision.
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+
+	err := dec.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func clone(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(data)
+}
@@ -0,0 +1,391 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// The assertJSON() helper fails the test if two JSON documents aren't equal, ignoring the order of members.
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var g, w any
+
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid result %s: %v", got, err)
+	}
+
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("invalid expectation %s: %v", want, err)
+	}
+
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string // Expected document, empty if the patch must fail.
+		test  bool   // The patch must fail with ErrTestFailed.
+	}{
+		// Examples of RFC 6902, Appendix A.
+		{
+			name:  "A.1 adding an object member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:  `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:  "A.2 adding an array element",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:  `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:  "A.3 removing an object member",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			want:  `{"foo": "bar"}`,
+		},
+		{
+			name:  "A.4 removing an array element",
+			doc:   `{"foo": ["bar", "qux", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+			want:  `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:  "A.5 replacing a value",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:  `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:  "A.6 moving a value",
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:  "A.7 moving an array element",
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:  "A.8 testing a value: success",
+			doc:   `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			want:  `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:  "A.9 testing a value: error",
+			doc:   `{"baz": "qux"}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			test:  true,
+		},
+		{
+			name:  "A.10 adding a nested member object",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			want:  `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:  "A.11 ignoring unrecognized elements",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			want:  `{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			name:  "A.12 adding to a nonexistent target",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+		},
+		{
+			name:  "A.14 escape ordering",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": 10}]`,
+			want:  `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:  "A.15 comparing strings and numbers",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": "10"}]`,
+			test:  true,
+		},
+		{
+			name:  "A.16 adding an array value",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:  `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+
+		// Pointers.
+		{
+			name:  "escaped slash in a member name",
+			doc:   `{"a/b": 1}`,
+			patch: `[{"op": "replace", "path": "/a~1b", "value": 2}]`,
+			want:  `{"a/b": 2}`,
+		},
+		{
+			name:  "escaped tilde in a member name",
+			doc:   `{"m~n": 1}`,
+			patch: `[{"op": "remove", "path": "/m~0n"}]`,
+			want:  `{}`,
+		},
+		{
+			name:  "empty member name",
+			doc:   `{"": 1}`,
+			patch: `[{"op": "replace", "path": "/", "value": 2}]`,
+			want:  `{"": 2}`,
+		},
+		{
+			name:  "pointer without leading slash",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "remove", "path": "foo"}]`,
+		},
+		{
+			name:  "whole document",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "replace", "path": "", "value": [1]}]`,
+			want:  `[1]`,
+		},
+
+		// Array indexes.
+		{
+			name:  "add at the end by index",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "add", "path": "/foo/2", "value": 3}]`,
+			want:  `{"foo": [1, 2, 3]}`,
+		},
+		{
+			name:  "add after the end",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "add", "path": "/foo/3", "value": 3}]`,
+		},
+		{
+			name:  "leading zero index",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "replace", "path": "/foo/01", "value": 3}]`,
+		},
+		{
+			name:  "zero index",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "replace", "path": "/foo/0", "value": 3}]`,
+			want:  `{"foo": [3, 2]}`,
+		},
+		{
+			name:  "signed index",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "remove", "path": "/foo/+1"}]`,
+		},
+		{
+			name:  "negative index",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "remove", "path": "/foo/-1"}]`,
+		},
+		{
+			name:  "dash index only for add",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "replace", "path": "/foo/-", "value": 3}]`,
+		},
+		{
+			name:  "remove out of bounds",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "remove", "path": "/foo/2"}]`,
+		},
+
+		// Move and copy.
+		{
+			name:  "move into a child",
+			doc:   `{"foo": {"bar": 1}}`,
+			patch: `[{"op": "move", "from": "/foo", "path": "/foo/bar/baz"}]`,
+		},
+		{
+			name:  "move to a sibling with a common prefix",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "move", "from": "/foo", "path": "/foobar"}]`,
+			want:  `{"foobar": 1}`,
+		},
+		{
+			name:  "move onto itself",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "move", "from": "/foo", "path": "/foo"}]`,
+			want:  `{"foo": 1}`,
+		},
+		{
+			name:  "copy is a deep copy",
+			doc:   `{"foo": {"bar": 1}}`,
+			patch: `[{"op": "copy", "from": "/foo", "path": "/baz"}, {"op": "replace", "path": "/baz/bar", "value": 2}]`,
+			want:  `{"foo": {"bar": 1}, "baz": {"bar": 2}}`,
+		},
+
+		// Test.
+		{
+			name:  "numbers are equal by value",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "test", "path": "/foo", "value": 1.0}, {"op": "test", "path": "/foo", "value": 1e0}]`,
+			want:  `{"foo": 1}`,
+		},
+		{
+			name:  "different numbers",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "test", "path": "/foo", "value": 1.5}]`,
+			test:  true,
+		},
+		{
+			name:  "objects are equal regardless of the order of members",
+			doc:   `{"foo": {"a": 1, "b": [1, {"c": null}]}}`,
+			patch: `[{"op": "test", "path": "/foo", "value": {"b": [1, {"c": null}], "a": 1.0}}]`,
+			want:  `{"foo": {"a": 1, "b": [1, {"c": null}]}}`,
+		},
+		{
+			name:  "arrays are compared in order",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "test", "path": "/foo", "value": [2, 1]}]`,
+			test:  true,
+		},
+		{
+			name:  "test of a missing member",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "test", "path": "/bar", "value": 1}]`,
+		},
+
+		// Operations.
+		{
+			name:  "missing value",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "add", "path": "/bar"}]`,
+		},
+		{
+			name:  "null value",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "add", "path": "/bar", "value": null}]`,
+			want:  `{"foo": 1, "bar": null}`,
+		},
+		{
+			name:  "unknown operation",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "increment", "path": "/foo"}]`,
+		},
+		{
+			name:  "replace of a missing member",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "replace", "path": "/bar", "value": 1}]`,
+		},
+		{
+			name:  "remove the whole document",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "remove", "path": ""}]`,
+		},
+		{
+			name:  "a failing operation stops the patch",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "add", "path": "/bar", "value": 2}, {"op": "test", "path": "/foo", "value": 2}, {"op": "remove", "path": "/foo"}]`,
+			test:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var operations []Operation
+
+			if err := json.Unmarshal([]byte(tt.patch), &operations); err != nil {
+				t.Fatalf("invalid patch: %v", err)
+			}
+
+			got, err := Apply([]byte(tt.doc), operations)
+
+			switch {
+			case tt.test:
+				if !errors.Is(err, ErrTestFailed) {
+					t.Fatalf("got error %v; want ErrTestFailed", err)
+				}
+			case tt.want == "":
+				if err == nil {
+					t.Fatalf("got %s; want an error", got)
+				}
+
+				if errors.Is(err, ErrTestFailed) {
+					t.Fatalf("got ErrTestFailed; want another error")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				assertJSON(t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyKeepsLargeIntegers(t *testing.T) {
+	got, err := Apply([]byte(`{"id": 9007199254740993}`), []Operation{
+		{Op: "test", Path: "/id", Value: json.RawMessage(`9007199254740993`)},
+		{Op: "add", Path: "/other", Value: json.RawMessage(`1`)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"id":9007199254740993,"other":1}`
+	if string(got) != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// Examples of RFC 7396, Appendix A, and the example of section 3.
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"replace a member", `{"a": "b"}`, `{"a": "c"}`, `{"a": "c"}`},
+		{"add a member", `{"a": "b"}`, `{"b": "c"}`, `{"a": "b", "b": "c"}`},
+		{"null removes a member", `{"a": "b"}`, `{"a": null}`, `{}`},
+		{"null removes only its member", `{"a": "b", "b": "c"}`, `{"a": null}`, `{"b": "c"}`},
+		{"arrays are replaced", `{"a": ["b"]}`, `{"a": "c"}`, `{"a": "c"}`},
+		{"values are replaced by arrays", `{"a": "c"}`, `{"a": ["b"]}`, `{"a": ["b"]}`},
+		{"nested objects are merged", `{"a": {"b": "c"}}`, `{"a": {"b": "d", "c": null}}`, `{"a": {"b": "d"}}`},
+		{"arrays of objects are replaced", `{"a": [{"b": "c"}]}`, `{"a": [1]}`, `{"a": [1]}`},
+		{"array patch replaces the document", `["a", "b"]`, `["c", "d"]`, `["c", "d"]`},
+		{"object patch replaces an array", `{"a": "b"}`, `["c"]`, `["c"]`},
+		{"null patch replaces the document", `{"a": "foo"}`, `null`, `null`},
+		{"string patch replaces the document", `{"a": "foo"}`, `"bar"`, `"bar"`},
+		{"null members of new objects are removed", `{"e": null}`, `{"a": 1}`, `{"e": null, "a": 1}`},
+		{"object patch on an array", `[1, 2]`, `{"a": "b", "c": null}`, `{"a": "b"}`},
+		{"null removed deep inside new objects", `{}`, `{"a": {"bb": {"ccc": null}}}`, `{"a": {"bb": {}}}`},
+		{
+			"section 3 example",
+			`{"title": "Goodbye!", "author": {"givenName": "John", "familyName": "Doe"}, "tags": ["example", "sample"], "content": "This will be unchanged"}`,
+			`{"title": "Hello!", "phoneNumber": "+01-123-456-7890", "author": {"familyName": null}, "tags": ["example"]}`,
+			`{"title": "Hello!", "author": {"givenName": "John"}, "tags": ["example"], "content": "This will be unchanged", "phoneNumber": "+01-123-456-7890"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestMergeInvalidJSON(t *testing.T) {
+	_, err := Merge([]byte(`{"a": 1}`), []byte(`{"a":`))
+	if err == nil {
+		t.Fatal("got no error; want an error for an invalid patch")
+	}
+}
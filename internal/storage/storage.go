@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+var (
+	ErrNotFound   = errors.New("file not found")
+	ErrInvalidKey = errors.New("invalid key")
+)
+
+// File is a stored file opened for reading. It can be served with http.ServeContent().
+type File interface {
+	io.ReadSeekCloser
+	Stat() (fs.FileInfo, error)
+}
+
+// Storage interface is implemented by the backends which store uploaded files, e.g. movie posters.
+// Files are addressed by slash separated keys like "posters/1/original".
+type Storage interface {
+	Put(key string, r io.Reader) error
+	Open(key string) (File, error)
+	Delete(key string) error
+}
+
+// Local struct stores the files in a directory of the local filesystem.
+type Local struct {
+	root string
+}
+
+// NewLocal returns a Local storage which stores the files below the root directory.
+// The directory is created if it doesn't exist.
+func NewLocal(root string) (*Local, error) {
+	err := os.MkdirAll(root, 0o750)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Local{root: root}, nil
+}
+
+// Put stores the content of r under the key. The file is written to a temporary file first and
+// renamed afterwards, so readers never see a partially written file.
+func (l *Local) Put(key string, r io.Reader) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(name), 0o750)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// Open opens the file stored under the key for reading.
+func (l *Local) Open(key string) (File, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// Delete removes the file stored under the key. Deleting a file which doesn't exist isn't an error.
+func (l *Local) Delete(key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// The path() method returns the path of the file of a key. Keys which would leave the root
+// directory (e.g. containing "..") are rejected.
+func (l *Local) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." || path.Clean(key) != key {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
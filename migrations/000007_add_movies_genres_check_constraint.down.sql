@@ -0,0 +1,2 @@
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_genres_check;
+UPDATE movies SET genres = (SELECT STRING_AGG(value, ',') FROM OPENJSON(movies.genres)) WHERE genres IS NOT NULL;
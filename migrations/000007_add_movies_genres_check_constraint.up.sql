@@ -0,0 +1,3 @@
+UPDATE movies SET genres = '["' + REPLACE(genres, ',', '","') + '"]' WHERE genres IS NOT NULL AND ISJSON(genres) = 0;
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_genres_check;
+ALTER TABLE movies ADD CONSTRAINT movies_genres_check CHECK (genres IS NULL OR ISJSON(genres) = 1);
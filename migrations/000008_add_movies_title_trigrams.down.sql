@@ -0,0 +1,2 @@
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_title_trigrams_check;
+ALTER TABLE movies DROP COLUMN IF EXISTS title_trigrams;
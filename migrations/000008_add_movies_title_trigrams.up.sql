@@ -0,0 +1 @@
+ALTER TABLE movies ADD [title_trigrams] nvarchar(max) NULL CONSTRAINT movies_title_trigrams_check CHECK (title_trigrams IS NULL OR ISJSON(title_trigrams) = 1);
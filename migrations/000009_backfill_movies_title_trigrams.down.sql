@@ -0,0 +1 @@
+UPDATE movies SET title_trigrams = NULL;
@@ -0,0 +1,22 @@
+-- Backfill the trigrams of the existing movies. Each lower-cased word is padded with two leading spaces
+-- and one trailing space, the same way the application calculates them.
+WITH words AS (
+	SELECT m.id, N'  ' + w.value + N' ' AS word
+	FROM movies m
+	CROSS APPLY STRING_SPLIT(LOWER(m.title), N' ') AS w
+	WHERE w.value <> N''
+),
+positions AS (
+	SELECT id, word, 1 AS n FROM words
+	UNION ALL
+	SELECT id, word, n + 1 FROM positions WHERE n + 3 <= DATALENGTH(word) / 2
+),
+trigrams AS (
+	SELECT DISTINCT id, SUBSTRING(word, n, 3) AS trigram FROM positions
+)
+UPDATE movies
+SET title_trigrams = (
+	SELECT N'[' + STRING_AGG(N'"' + STRING_ESCAPE(t.trigram, 'json') + N'"', N',') WITHIN GROUP (ORDER BY t.trigram) + N']'
+	FROM trigrams t
+	WHERE t.id = movies.id
+);
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE code = 'movies:admin';
+DROP INDEX IF EXISTS movies_deleted_at_idx ON movies;
+ALTER TABLE movies DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,7 @@
+ALTER TABLE movies ADD [deleted_at] datetime NULL;
+
+CREATE INDEX movies_deleted_at_idx ON movies (deleted_at);
+
+INSERT INTO permissions (code)
+VALUES
+  ('movies:admin');
@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS movie_revisions;
+-- The table is the target of OUTPUT ... INTO clauses on the movies table,
+-- therefore it must not have foreign key or check constraints.
+CREATE TABLE [movie_revisions] (
+  [movie_id] bigint NOT NULL,
+  [version] int NOT NULL,
+  [title] nvarchar(255) NOT NULL,
+  [year] int NOT NULL,
+  [runtime] int NOT NULL,
+  [genres] nvarchar(max),
+  [user_id] bigint NULL,
+  [created_at] datetime NOT NULL DEFAULT (getdate()),
+  CONSTRAINT PK_movie_revisions PRIMARY KEY (movie_id, version)
+);
+
+-- Store the current version of all existing movies as their first known revision.
+INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, user_id, created_at)
+SELECT id, version, title, year, runtime, genres, NULL, created_at
+FROM movies;
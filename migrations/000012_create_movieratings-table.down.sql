@@ -0,0 +1,2 @@
+DELETE FROM permissions WHERE code = 'reviews:write';
+DROP TABLE IF EXISTS movie_ratings;
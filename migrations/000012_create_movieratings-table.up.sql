@@ -0,0 +1,25 @@
+DROP TABLE IF EXISTS movie_ratings;
+CREATE TABLE [movie_ratings] (
+  [movie_id] bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  [user_id] bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+  [rating] tinyint NOT NULL,
+  [review] nvarchar(max) NULL,
+  [created_at] datetime NOT NULL DEFAULT (getdate()),
+  [updated_at] datetime NOT NULL DEFAULT (getdate()),
+  CONSTRAINT PK_movie_ratings PRIMARY KEY (movie_id, user_id),
+  CONSTRAINT movie_ratings_rating_check CHECK (rating BETWEEN 1 AND 10)
+);
+
+CREATE INDEX movie_ratings_user_id_idx ON movie_ratings (user_id);
+
+INSERT INTO permissions (code)
+VALUES
+  ('reviews:write');
+
+-- Grant the new permission to all users which are already allowed to read movies.
+INSERT INTO userpermissions (user_id, permission_id)
+SELECT up.user_id, p.id
+FROM userpermissions up
+INNER JOIN permissions r ON r.id = up.permission_id AND r.code = 'movies:read'
+CROSS JOIN permissions p
+WHERE p.code = 'reviews:write';
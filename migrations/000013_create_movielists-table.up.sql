@@ -0,0 +1,29 @@
+DROP TABLE IF EXISTS movie_list_entries;
+DROP TABLE IF EXISTS movie_lists;
+CREATE TABLE [movie_lists] (
+  [id] bigint PRIMARY KEY IDENTITY(1, 1),
+  [created_at] datetime NOT NULL DEFAULT (getdate()),
+  [user_id] bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+  [kind] nvarchar(20) NOT NULL,
+  [name] nvarchar(255) NOT NULL,
+  [description] nvarchar(1000) NOT NULL DEFAULT (''),
+  [visibility] nvarchar(20) NOT NULL DEFAULT ('private'),
+  [slug] char(26) UNIQUE NOT NULL,
+  [version] int NOT NULL DEFAULT 1,
+  CONSTRAINT movie_lists_kind_check CHECK (kind IN ('watchlist', 'custom')),
+  CONSTRAINT movie_lists_visibility_check CHECK (visibility IN ('private', 'public'))
+);
+
+-- Every user has at most one built-in watchlist.
+CREATE UNIQUE INDEX movie_lists_watchlist_idx ON movie_lists (user_id) WHERE kind = 'watchlist';
+
+CREATE TABLE [movie_list_entries] (
+  [list_id] bigint NOT NULL REFERENCES movie_lists ON DELETE CASCADE,
+  [movie_id] bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  [position] int NOT NULL,
+  [note] nvarchar(1000) NOT NULL DEFAULT (''),
+  [added_at] datetime NOT NULL DEFAULT (getdate()),
+  CONSTRAINT PK_movie_list_entries PRIMARY KEY (list_id, movie_id)
+);
+
+CREATE INDEX movie_list_entries_movie_id_idx ON movie_list_entries (movie_id);
@@ -0,0 +1,25 @@
+DROP TABLE IF EXISTS movie_credits;
+DROP TABLE IF EXISTS people;
+CREATE TABLE [people] (
+  [id] bigint PRIMARY KEY IDENTITY(1, 1),
+  [created_at] datetime NOT NULL DEFAULT (getdate()),
+  [name] nvarchar(255) NOT NULL,
+  [birth_year] int NULL,
+  [biography] nvarchar(max) NOT NULL DEFAULT (''),
+  [version] int NOT NULL DEFAULT 1
+);
+
+CREATE INDEX people_name_idx ON people (name);
+
+CREATE TABLE [movie_credits] (
+  [id] bigint PRIMARY KEY IDENTITY(1, 1),
+  [movie_id] bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  [person_id] bigint NOT NULL REFERENCES people ON DELETE CASCADE,
+  [role] nvarchar(20) NOT NULL,
+  [character] nvarchar(255) NOT NULL DEFAULT (''),
+  [billing_order] int NOT NULL DEFAULT 0,
+  CONSTRAINT movie_credits_role_check CHECK (role IN ('actor', 'director', 'writer', 'composer')),
+  CONSTRAINT movie_credits_unique UNIQUE (movie_id, person_id, role, character)
+);
+
+CREATE INDEX movie_credits_person_id_idx ON movie_credits (person_id);
@@ -0,0 +1 @@
+ALTER TABLE movies ADD [poster_updated_at] datetime NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE [movie_translations] (
+  [movie_id] bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  [language] nvarchar(35) NOT NULL,
+  [title] nvarchar(500) NOT NULL,
+  [overview] nvarchar(max) NOT NULL DEFAULT (''),
+  [title_trigrams] nvarchar(max) NULL,
+  [created_at] datetime NOT NULL DEFAULT (getdate()),
+  [updated_at] datetime NOT NULL DEFAULT (getdate()),
+  CONSTRAINT PK_movie_translations PRIMARY KEY (movie_id, language),
+  CONSTRAINT movie_translations_title_trigrams_check CHECK (title_trigrams IS NULL OR ISJSON(title_trigrams) = 1)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS movies_merged_into_idx ON movies;
+ALTER TABLE movies DROP COLUMN IF EXISTS merged_into;
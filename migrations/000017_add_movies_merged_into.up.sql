@@ -0,0 +1,3 @@
+ALTER TABLE movies ADD [merged_into] bigint NULL;
+
+CREATE INDEX movies_merged_into_idx ON movies (merged_into);
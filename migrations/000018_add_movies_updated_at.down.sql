@@ -0,0 +1,2 @@
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_updated_at_default;
+ALTER TABLE movies DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1 @@
+ALTER TABLE movies ADD [updated_at] datetime NOT NULL CONSTRAINT movies_updated_at_default DEFAULT (getdate());
@@ -0,0 +1 @@
+UPDATE movies SET updated_at = created_at;
@@ -0,0 +1,7 @@
+-- Backfill the modification time of the existing movies from their latest revision.
+UPDATE movies
+SET updated_at = COALESCE((
+	SELECT MAX(movie_revisions.created_at)
+	FROM movie_revisions
+	WHERE movie_revisions.movie_id = movies.id
+), movies.created_at);
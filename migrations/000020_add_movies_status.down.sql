@@ -0,0 +1,5 @@
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_status_check;
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_status_default;
+ALTER TABLE movies DROP COLUMN IF EXISTS release_date;
+ALTER TABLE movies DROP COLUMN IF EXISTS [status];
+ALTER TABLE movie_revisions DROP COLUMN IF EXISTS release_date, [status];
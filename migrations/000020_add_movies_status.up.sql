@@ -0,0 +1,7 @@
+ALTER TABLE movies ADD [status] nvarchar(20) NOT NULL CONSTRAINT movies_status_default DEFAULT ('released')
+    CONSTRAINT movies_status_check CHECK ([status] IN ('announced', 'in_production', 'released'));
+ALTER TABLE movies ADD [release_date] date NULL;
+
+-- The revisions stored before have NULL, as they don't record the status and release date.
+ALTER TABLE movie_revisions ADD [status] nvarchar(20) NULL;
+ALTER TABLE movie_revisions ADD [release_date] date NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS movies_status_release_date_idx ON movies;
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_year_check;
+ALTER TABLE movies ADD CONSTRAINT movies_year_check CHECK (year BETWEEN 1888 AND datepart(year, getdate()));
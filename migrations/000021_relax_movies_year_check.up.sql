@@ -0,0 +1,3 @@
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_year_check;
+ALTER TABLE movies ADD CONSTRAINT movies_year_check CHECK (year >= 1888 AND ([status] <> 'released' OR year <= datepart(year, getdate())));
+CREATE INDEX movies_status_release_date_idx ON movies ([status], release_date);
@@ -0,0 +1,7 @@
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_plot_default;
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_certifications_check;
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_spoken_languages_check;
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_production_countries_check;
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_original_language_default;
+ALTER TABLE movies DROP COLUMN IF EXISTS plot, certifications, spoken_languages, production_countries, original_language;
+ALTER TABLE movie_revisions DROP COLUMN IF EXISTS external_ids, plot, certifications, spoken_languages, production_countries, original_language;
@@ -0,0 +1,16 @@
+ALTER TABLE movies ADD [original_language] nvarchar(3) NOT NULL CONSTRAINT movies_original_language_default DEFAULT ('');
+ALTER TABLE movies ADD [production_countries] nvarchar(max) NULL
+    CONSTRAINT movies_production_countries_check CHECK (production_countries IS NULL OR ISJSON(production_countries) = 1);
+ALTER TABLE movies ADD [spoken_languages] nvarchar(max) NULL
+    CONSTRAINT movies_spoken_languages_check CHECK (spoken_languages IS NULL OR ISJSON(spoken_languages) = 1);
+ALTER TABLE movies ADD [certifications] nvarchar(max) NULL
+    CONSTRAINT movies_certifications_check CHECK (certifications IS NULL OR ISJSON(certifications) = 1);
+ALTER TABLE movies ADD [plot] nvarchar(max) NOT NULL CONSTRAINT movies_plot_default DEFAULT ('');
+
+-- The revisions stored before have NULL, as they don't record the metadata and external IDs.
+ALTER TABLE movie_revisions ADD [original_language] nvarchar(3) NULL;
+ALTER TABLE movie_revisions ADD [production_countries] nvarchar(max) NULL;
+ALTER TABLE movie_revisions ADD [spoken_languages] nvarchar(max) NULL;
+ALTER TABLE movie_revisions ADD [certifications] nvarchar(max) NULL;
+ALTER TABLE movie_revisions ADD [plot] nvarchar(max) NULL;
+ALTER TABLE movie_revisions ADD [external_ids] nvarchar(max) NULL;
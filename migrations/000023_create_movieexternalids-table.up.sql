@@ -0,0 +1,7 @@
+CREATE TABLE [movie_external_ids] (
+  [movie_id] bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  [source] nvarchar(20) NOT NULL,
+  [external_id] nvarchar(50) NOT NULL,
+  CONSTRAINT PK_movie_external_ids PRIMARY KEY (movie_id, source),
+  CONSTRAINT movie_external_ids_source_external_id_key UNIQUE (source, external_id)
+);